@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newAuthCommand() *cobra.Command {
+	var reset bool
+
+	command := &cobra.Command{
+		Use:   "auth",
+		Short: "Authenticate with Trakt and store the access token",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if reset {
+				traktAPI := newTraktAPI(config.Current())
+				if err := traktAPI.ClearAuthentication(); err != nil {
+					return fmt.Errorf("could not remove stored Trakt access token: %w", err)
+				}
+			}
+
+			_, err := authenticateWithTrakt(cmd.Context(), config.Current())
+			return err
+		},
+	}
+
+	command.Flags().BoolVar(&reset, "reset", false, "discard the stored access token and authorize again")
+	return command
+}
+
+func newWhoamiCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "whoami",
+		Short: "Show the Trakt user that series-cleanup is authenticated as",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			traktAPI, err := authenticateWithTrakt(cmd.Context(), config.Current())
+			if err != nil {
+				return err
+			}
+
+			settings, err := traktAPI.GetUserSettings(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("could not get Trakt user settings: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Username: %v\n", settings.User.Username)
+			if settings.User.Name != "" {
+				fmt.Fprintf(out, "Name:     %v\n", settings.User.Name)
+			}
+			fmt.Fprintf(out, "VIP:      %v\n", settings.User.VIP)
+			return nil
+		},
+	}
+}
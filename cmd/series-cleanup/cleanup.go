@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/audit"
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/filesystem"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/bjw-s/series-cleanup/internal/mediafile"
+	"github.com/bjw-s/series-cleanup/internal/metrics"
+	"github.com/bjw-s/series-cleanup/internal/state"
+	"github.com/bjw-s/series-cleanup/internal/trakt"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+// openScanFolder opens the file system of scanFolder, which is a local folder or the URL of a
+// remote folder. It returns the file system and the path of scanFolder in it, which must be
+// closed by the caller. It fails when scanFolder is not an existing folder.
+func openScanFolder(settings *config.Settings, scanFolder string) (filesystem.FileSystem, string, error) {
+	fileSystem, root, err := filesystem.Open(scanFolder, settings.RemoteCredentials(scanFolder))
+	if err != nil {
+		return nil, "", err
+	}
+
+	info, err := fileSystem.Stat(root)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()):
+		err = fmt.Errorf("folder does not exist: %v", scanFolder)
+	case err != nil:
+		err = fmt.Errorf("could not open folder %v: %w", scanFolder, err)
+	}
+	if err != nil {
+		fileSystem.Close()
+		return nil, "", err
+	}
+	return fileSystem, root, nil
+}
+
+// collectTvShowFiles collects the TV show files below root, the path of scanFolder in fileSystem
+func collectTvShowFiles(ctx context.Context, settings *config.Settings, fileSystem filesystem.FileSystem, scanFolder, root string, result *runResult) ([]*mediafile.TVShowFile, error) {
+	// The files in a folder find their subtitle files in the listing that was read by the walk
+	fileSystem = filesystem.NewListingCache(fileSystem)
+	var tvShowFiles []*mediafile.TVShowFile
+	err := filesystem.Walk(fileSystem, root, func(path string, info fs.FileInfo, nestedErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if nestedErr != nil {
+			if path == root {
+				return nestedErr
+			}
+			result.AddError(path, nestedErr)
+			if info != nil && info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		if file := collectTvShowFile(settings, fileSystem, scanFolder, path, result); file != nil {
+			tvShowFiles = append(tvShowFiles, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tvShowFiles, nil
+}
+
+// collectQueuedTvShowFiles collects the TV show files from paths in scanFolder instead of walking
+// the whole scan folder. Paths that no longer exist are ignored.
+func collectQueuedTvShowFiles(ctx context.Context, settings *config.Settings, fileSystem filesystem.FileSystem, scanFolder string, paths []string, result *runResult) ([]*mediafile.TVShowFile, error) {
+	// Queued files in the same folder share its listing
+	fileSystem = filesystem.NewListingCache(fileSystem)
+	var tvShowFiles []*mediafile.TVShowFile
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if _, err := fileSystem.Stat(path); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				result.AddError(path, err)
+			}
+			continue
+		}
+
+		if file := collectTvShowFile(settings, fileSystem, scanFolder, path, result); file != nil {
+			tvShowFiles = append(tvShowFiles, file)
+		}
+	}
+	return tvShowFiles, nil
+}
+
+// collectTvShowFile parses the media file at path and applies the overrides. It returns nil when
+// path is not a media file, could not be parsed or is skipped by an override.
+func collectTvShowFile(settings *config.Settings, fileSystem filesystem.FileSystem, scanFolder string, path string, result *runResult) *mediafile.TVShowFile {
+	fileName := filepath.Base(path)
+	if strings.HasPrefix(fileName, ".") {
+		return nil
+	}
+
+	if !mediafile.IsMediaFile(path) {
+		return nil
+	}
+	metrics.FileScanned(scanFolder)
+
+	file, err := mediafile.NewTVShowFile(fileSystem, path, settings.CompiledFolderRegex())
+	if err != nil {
+		logger.Error("Could not parse TV show file",
+			zap.String("file", path),
+			zap.Error(err),
+		)
+		metrics.FileUnrecognized(scanFolder)
+		result.AddUnrecognized(path, err)
+		return nil
+	}
+	metrics.FileParsed(scanFolder)
+	if file == nil {
+		return nil
+	}
+
+	// Add mappings
+	skipShow := false
+	var skipSeasons []int
+
+	for _, item := range settings.Overrides {
+		parentFolderName := filepath.Base(filepath.Dir(file.Dir))
+		if strings.EqualFold(parentFolderName, item.Folder) {
+			file.Mappings = item.Mapping
+			skipShow = item.Skip
+			skipSeasons = item.SkipSeasons
+			break
+		}
+	}
+
+	if skipShow {
+		logger.Debug("Skipped",
+			zap.String("show", file.Show),
+			zap.String("file", file.Filename),
+			zap.String("reason", reasonShowSkipped),
+		)
+		result.AddDecision(scanFolder, file, decision{actionSkipped, reasonShowSkipped})
+		return nil
+	}
+
+	if lo.Contains(skipSeasons, file.Season) {
+		logger.Debug("Skipped",
+			zap.String("show", file.Show),
+			zap.String("file", file.Filename),
+			zap.String("reason", reasonSeasonSkipped),
+		)
+		result.AddDecision(scanFolder, file, decision{actionSkipped, reasonSeasonSkipped})
+		return nil
+	}
+
+	return file
+}
+
+// episodeCleaner decides what to do with TV show files based on the watched state of a Trakt user
+type episodeCleaner struct {
+	// settings is the configuration of the run, so a reload does not affect a run in progress
+	settings *config.Settings
+	user     *trakt.User
+	resolver *trakt.ShowResolver
+	// auditLog records every removed file, it is nil when the audit log is disabled
+	auditLog *audit.Log
+
+	// approvals contains the reviewed and pending deletions by path, when approval is enabled
+	approvals map[string]state.Approval
+
+	mutex            sync.Mutex
+	pendingApprovals []state.Approval
+}
+
+// loadState loads the approvals from the state database
+func (cleaner *episodeCleaner) loadState(store *state.Store) error {
+	if cleaner.settings.Approval.Enabled {
+		var err error
+		cleaner.approvals, err = store.Approvals()
+		if err != nil {
+			return fmt.Errorf("could not load approvals: %w", err)
+		}
+	}
+	return nil
+}
+
+// PendingApprovals returns the deletions that require approval
+func (cleaner *episodeCleaner) PendingApprovals() []state.Approval {
+	cleaner.mutex.Lock()
+	defer cleaner.mutex.Unlock()
+	return append([]state.Approval(nil), cleaner.pendingApprovals...)
+}
+
+// approvalDecision returns what to do with a file that can be deleted when approval is required.
+// Files that have not been reviewed yet are registered as pending approval.
+func (cleaner *episodeCleaner) approvalDecision(mediafile *mediafile.TVShowFile, watchedShow *trakt.WatchedShow, episode *trakt.Episode) (decision, bool) {
+	path := filepath.Join(mediafile.Dir, mediafile.Filename)
+	approval, ok := cleaner.approvals[path]
+	if ok && approval.Status == state.ApprovalApproved {
+		return decision{actionDeleted, reasonDeletionApproved}, true
+	}
+
+	cleaner.mutex.Lock()
+	cleaner.pendingApprovals = append(cleaner.pendingApprovals, state.Approval{
+		Path:        path,
+		Folder:      showFolderName(mediafile),
+		Show:        watchedShow.Show.Title,
+		TraktID:     watchedShow.Show.IDS.Trakt,
+		Season:      mediafile.Season,
+		Episode:     mediafile.Episode,
+		Size:        mediafile.Size(),
+		LastWatched: episode.LastWatched,
+		Plays:       episode.Plays,
+		RequestedAt: time.Now(),
+	})
+	cleaner.mutex.Unlock()
+
+	if ok && approval.Status == state.ApprovalRejected {
+		return decision{actionKept, reasonDeletionRejected}, false
+	}
+	return decision{actionPendingApproval, reasonWaitingForApproval}, false
+}
+
+// showFolderName returns the name of the show folder a TV show file is in, as used by the overrides
+func showFolderName(mediafile *mediafile.TVShowFile) string {
+	return filepath.Base(filepath.Dir(mediafile.Dir))
+}
+
+// processTvShowFile decides what to do with a TV show file and removes it when it can be deleted.
+// downloadedAt is when the file was first seen in the scan folder.
+func (cleaner *episodeCleaner) processTvShowFile(ctx context.Context, mediafile *mediafile.TVShowFile, downloadedAt time.Time) (decision, error) {
+	logger.Debug("Processing tv show file",
+		zap.String("dir", mediafile.Dir),
+		zap.String("file", mediafile.Filename),
+	)
+
+	var watchedShow *trakt.WatchedShow
+	if mediafile.Mappings.TraktID != 0 {
+		watchedShow = cleaner.user.FindWatchedShowByTraktID(mediafile.Mappings.TraktID)
+	} else if mediafile.Mappings.TraktSlug != "" {
+		watchedShow = cleaner.user.FindWatchedShowBySlug(mediafile.Mappings.TraktSlug)
+	} else if mediafile.Mappings.IMDBID != "" {
+		watchedShow = cleaner.user.FindWatchedShowByIMDBID(mediafile.Mappings.IMDBID)
+	} else if mediafile.Mappings.TVDBID != 0 {
+		watchedShow = cleaner.user.FindWatchedShowByTVDBID(mediafile.Mappings.TVDBID)
+	} else if mediafile.Mappings.TMDBID != 0 {
+		watchedShow = cleaner.user.FindWatchedShowByTMDBID(mediafile.Mappings.TMDBID)
+	} else if mediafile.Mappings.TraktName != "" {
+		watchedShow = cleaner.findWatchedShowByName(ctx, mediafile.Mappings.TraktName)
+	} else {
+		watchedShow = cleaner.findWatchedShowByName(ctx, mediafile.Show)
+	}
+
+	if watchedShow == nil {
+		logger.Debug("Skipped",
+			zap.String("show", mediafile.Show),
+			zap.String("file", mediafile.Filename),
+			zap.String("reason", reasonShowUnwatched),
+		)
+		return decision{actionSkipped, reasonShowUnwatched}, nil
+	}
+
+	season := watchedShow.FindSeason(mediafile.Season)
+	if season == nil {
+		logger.Debug("Skipped",
+			zap.String("show", watchedShow.Show.Title),
+			zap.String("file", mediafile.Filename),
+			zap.String("reason", reasonSeasonUnwatched),
+		)
+		return decision{actionSkipped, reasonSeasonUnwatched}, nil
+	}
+
+	episode := season.FindEpisode(mediafile.Episode)
+	if episode == nil {
+		logger.Debug("Skipped",
+			zap.String("show", watchedShow.Show.Title),
+			zap.String("file", mediafile.Filename),
+			zap.String("reason", reasonEpisodeUnwatched),
+		)
+		return decision{actionSkipped, reasonEpisodeUnwatched}, nil
+	}
+
+	watchedBeforeTime := time.Now().Add(-time.Duration(int64(cleaner.settings.DeleteAfterHours) * int64(time.Hour)))
+	if reason := episodeKeepReason(cleaner.settings, episode, watchedBeforeTime); reason != "" {
+		logger.Debug("Kept",
+			zap.String("show", watchedShow.Show.Title),
+			zap.String("file", mediafile.Filename),
+			zap.String("reason", reason),
+		)
+		return decision{actionKept, reason}, nil
+	}
+
+	if cleaner.settings.DeleteAfterDownloadedHours > 0 {
+		downloadedBeforeTime := time.Now().Add(-time.Duration(int64(cleaner.settings.DeleteAfterDownloadedHours) * int64(time.Hour)))
+		if downloadedAt.After(downloadedBeforeTime) {
+			logger.Debug("Kept",
+				zap.String("show", watchedShow.Show.Title),
+				zap.String("file", mediafile.Filename),
+				zap.String("reason", reasonDownloadedTooRecently),
+			)
+			return decision{actionKept, reasonDownloadedTooRecently}, nil
+		}
+	}
+
+	if cleaner.settings.DryRun {
+		logger.Info("TV show file would have been removed",
+			zap.String("dir", mediafile.Dir),
+			zap.String("file", mediafile.Filename),
+		)
+		return decision{actionWouldDelete, reasonDryRun}, nil
+	}
+
+	deleteDecision := decision{actionDeleted, reasonWatched}
+	if cleaner.settings.Approval.Enabled {
+		var approved bool
+		deleteDecision, approved = cleaner.approvalDecision(mediafile, watchedShow, episode)
+		if !approved {
+			logger.Debug("Kept",
+				zap.String("show", watchedShow.Show.Title),
+				zap.String("file", mediafile.Filename),
+				zap.String("reason", deleteDecision.Reason),
+			)
+			return deleteDecision, nil
+		}
+	}
+
+	// Do not start new deletions once the run has been cancelled
+	if err := ctx.Err(); err != nil {
+		return decision{}, err
+	}
+
+	logger.Info("Removing tv show file",
+		zap.String("dir", mediafile.Dir),
+		zap.String("file", mediafile.Filename),
+	)
+	// The decision is returned along with a failed deletion, so the file still counts as a candidate
+	sidecars := mediafile.SubtitleFiles()
+	if err := mediafile.DeleteWithSubtitleFiles(); err != nil {
+		return deleteDecision, err
+	}
+
+	cleaner.recordDeletion(mediafile, sidecars, watchedShow, episode)
+	return deleteDecision, nil
+}
+
+// recordDeletion adds a removed file to the audit log.
+// Failures are logged, as the file has already been removed at this point.
+func (cleaner *episodeCleaner) recordDeletion(mediafile *mediafile.TVShowFile, sidecars []string, watchedShow *trakt.WatchedShow, episode *trakt.Episode) {
+	if cleaner.auditLog == nil {
+		return
+	}
+
+	// A play reported by a media server is attributed to its source and user instead of the Trakt user
+	users := []string{cleaner.user.Name}
+	if episode.WatchedBy != "" {
+		users = []string{episode.WatchedBy}
+	}
+
+	ids := watchedShow.Show.IDS
+	err := cleaner.auditLog.Record(audit.Entry{
+		Time:     time.Now(),
+		Path:     filepath.Join(mediafile.Dir, mediafile.Filename),
+		Sidecars: sidecars,
+		Size:     mediafile.Size(),
+		Show:     watchedShow.Show.Title,
+		ShowIDs: audit.ShowIDs{
+			Trakt: ids.Trakt,
+			Slug:  ids.Slug,
+			TVDB:  ids.TVDB,
+			IMDB:  ids.IMDB,
+			TMDB:  ids.TMDB,
+		},
+		Season:       mediafile.Season,
+		Episode:      mediafile.Episode,
+		FirstWatched: episode.FirstWatched,
+		LastWatched:  episode.LastWatched,
+		Plays:        episode.Plays,
+		Policy: audit.Policy{
+			DeleteBasedOn:              cleaner.settings.DeleteBasedOn,
+			DeleteAfterHours:           cleaner.settings.DeleteAfterHours,
+			DeleteAfterDownloadedHours: cleaner.settings.DeleteAfterDownloadedHours,
+			MinPlays:                   cleaner.settings.MinPlays,
+		},
+		Users: users,
+	})
+	if err != nil {
+		logger.Error("Could not record removed file in the audit log",
+			zap.String("file", mediafile.Filename),
+			zap.Error(err),
+		)
+	}
+}
+
+func (cleaner *episodeCleaner) findWatchedShowByName(ctx context.Context, name string) *trakt.WatchedShow {
+	user := cleaner.user
+	resolver := cleaner.resolver
+	if watchedShow := user.FindWatchedShowByName(name); watchedShow != nil {
+		return watchedShow
+	}
+
+	if cleaner.settings.Matching.SimilarityThreshold > 0 {
+		if watchedShow := user.FindWatchedShowBySimilarName(name, cleaner.settings.Matching.SimilarityThreshold); watchedShow != nil {
+			logger.Debug("Matched show by similar name",
+				zap.String("show", name),
+				zap.String("match", watchedShow.Show.Title),
+			)
+			return watchedShow
+		}
+	}
+
+	if resolver != nil {
+		traktID, err := resolver.Resolve(ctx, name)
+		if err != nil {
+			logger.Error("Could not search Trakt for show",
+				zap.String("show", name),
+				zap.Error(err),
+			)
+			return nil
+		}
+		if traktID != 0 {
+			return user.FindWatchedShowByTraktID(traktID)
+		}
+	}
+
+	return nil
+}
+
+// episodeKeepReason returns why a watched episode should be kept, or an empty string if it can be removed
+func episodeKeepReason(settings *config.Settings, episode *trakt.Episode, watchedBeforeTime time.Time) string {
+	switch settings.DeleteBasedOn {
+	case "firstWatched":
+		if !episode.FirstWatchedBefore(watchedBeforeTime) {
+			return reasonWatchedTooRecently
+		}
+	case "playCount":
+		if episode.Plays < settings.MinPlays {
+			return reasonNotPlayedOftenEnough
+		}
+		if !episode.LastWatchedBefore(watchedBeforeTime) {
+			return reasonWatchedTooRecently
+		}
+	default:
+		if !episode.LastWatchedBefore(watchedBeforeTime) {
+			return reasonWatchedTooRecently
+		}
+	}
+	return ""
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/filesystem"
+	"github.com/bjw-s/series-cleanup/internal/state"
+	"github.com/bjw-s/series-cleanup/internal/trakt"
+)
+
+var update = flag.Bool("update", false, "update the expected results of the golden tests")
+
+// libraryRoot is the path of the scan folder in the in-memory file system of the golden tests
+const libraryRoot = "/tv"
+
+// TestCleanupGolden runs the cleanup of every folder in testdata/cleanup, which contains:
+//   - settings.yaml, the configuration
+//   - library.txt, the paths of the files in the scan folder, lines starting with # are ignored
+//   - watched.json, the watched shows as returned by Trakt, where {{hoursAgo N}} is replaced by
+//     the time N hours ago
+//   - expected.txt, the decisions, errors and the files that remain, which is written by
+//     go test -run TestCleanupGolden -update
+func TestCleanupGolden(t *testing.T) {
+	folders, err := filepath.Glob(filepath.Join("testdata", "cleanup", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, folder := range folders {
+		folder := folder
+		t.Run(filepath.Base(folder), func(t *testing.T) {
+			settings := readTestSettings(t, folder)
+			library := readLibrary(t, filepath.Join(folder, "library.txt"))
+			useStandIns(t, library, readWatchedShows(t, filepath.Join(folder, "watched.json")))
+
+			result, err := cleanupScanFolders(context.Background(), settings, nil)
+			if err != nil && !errors.Is(err, errPartialFailure) {
+				t.Fatalf("cleanupScanFolders() error = %v", err)
+			}
+
+			got := describeCleanup(result, library)
+			expectedFile := filepath.Join(folder, "expected.txt")
+			if *update {
+				if err := os.WriteFile(expectedFile, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(expectedFile)
+			if err != nil {
+				t.Fatalf("could not read the expected result, run with -update to create it: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("cleanup result differs from %s:\n%s\nwant:\n%s", expectedFile, got, want)
+			}
+		})
+	}
+}
+
+func TestDryRunKeepsPendingApprovals(t *testing.T) {
+	folder := filepath.Join("testdata", "cleanup", "watched")
+	settings := readTestSettings(t, folder)
+	settings.Approval.Enabled = true
+	settings.Approval.Token = "token"
+	useStandIns(t, readLibrary(t, filepath.Join(folder, "library.txt")), readWatchedShows(t, filepath.Join(folder, "watched.json")))
+
+	if _, err := cleanupScanFolders(context.Background(), settings, nil); err != nil && !errors.Is(err, errPartialFailure) {
+		t.Fatalf("cleanupScanFolders() error = %v", err)
+	}
+	planSettings := *settings
+	planSettings.DryRun = true
+	if _, err := cleanupScanFolders(context.Background(), &planSettings, nil); err != nil && !errors.Is(err, errPartialFailure) {
+		t.Fatalf("cleanupScanFolders() error = %v", err)
+	}
+
+	var approvals map[string]state.Approval
+	err := withStateStore(func(store *state.Store) (err error) {
+		approvals, err = store.Approvals()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approvals) != 2 {
+		t.Errorf("approvals = %+v, want the 2 pending approvals of the first run to be kept", approvals)
+	}
+}
+
+func TestUnrecognizedFilesDoNotFailRun(t *testing.T) {
+	folder := filepath.Join("testdata", "cleanup", "watched")
+	settings := readTestSettings(t, folder)
+	useStandIns(t, readLibrary(t, filepath.Join(folder, "library.txt")), readWatchedShows(t, filepath.Join(folder, "watched.json")))
+
+	result, err := cleanupScanFolders(context.Background(), settings, nil)
+	if err != nil {
+		t.Fatalf("cleanupScanFolders() error = %v, want unrecognized files not to fail the run", err)
+	}
+	if summary := result.Summary(); summary.Unrecognized != 1 || summary.Failed != 0 {
+		t.Errorf("summary = %+v, want 1 unrecognized and no failed files", summary)
+	}
+}
+
+// readTestSettings reads the configuration in folder without the SC_ environment variables of the
+// developer's shell, with libraryRoot as the scan folder and the audit log in a temporary folder
+func readTestSettings(t *testing.T, folder string) *config.Settings {
+	t.Helper()
+	for _, variable := range os.Environ() {
+		if name, _, _ := strings.Cut(variable, "="); strings.HasPrefix(name, "SC_") {
+			// Setenv restores the variable when the test is finished
+			t.Setenv(name, "")
+			os.Unsetenv(name)
+		}
+	}
+
+	settings, err := config.Read(folder, "")
+	if err != nil {
+		t.Fatalf("could not read the configuration: %v", err)
+	}
+	settings.ScanFolders = []string{libraryRoot}
+	settings.Audit.Path = filepath.Join(t.TempDir(), "audit.log")
+	return settings
+}
+
+// useStandIns makes runs use library as the scan folder at libraryRoot, user as the Trakt user and
+// a state database in a temporary configuration folder
+func useStandIns(t *testing.T, library *filesystem.Memory, user *trakt.User) {
+	t.Helper()
+	previousConfigFolder := configFolder
+	configFolder = t.TempDir()
+	runAuthenticateWithTrakt = func(context.Context, *config.Settings) (trakt.API, error) {
+		return trakt.API{}, nil
+	}
+	runGetTraktUser = func(context.Context, *config.Settings, trakt.API) (*trakt.User, error) {
+		return user, nil
+	}
+	runOpenScanFolder = func(_ *config.Settings, scanFolder string) (filesystem.FileSystem, string, error) {
+		if scanFolder != libraryRoot {
+			return nil, "", fmt.Errorf("folder does not exist: %v", scanFolder)
+		}
+		return library, libraryRoot, nil
+	}
+	t.Cleanup(func() {
+		configFolder = previousConfigFolder
+		runAuthenticateWithTrakt = authenticateWithTrakt
+		runGetTraktUser = getTraktUser
+		runOpenScanFolder = openScanFolder
+	})
+}
+
+// readLibrary returns an in-memory scan folder at libraryRoot with the files listed in path
+func readLibrary(t *testing.T, path string) *filesystem.Memory {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	library := filesystem.NewMemory()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		library.AddFile(filepath.Join(libraryRoot, line), int64(len(line)), time.Now().Add(-time.Hour))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return library
+}
+
+// readWatchedShows returns a Trakt user with the watched shows in path
+func readWatchedShows(t *testing.T, path string) *trakt.User {
+	t.Helper()
+	watched, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{
+		"hoursAgo": func(hours int) string {
+			return time.Now().Add(-time.Duration(hours) * time.Hour).UTC().Format(time.RFC3339)
+		},
+	}).ParseFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content bytes.Buffer
+	if err := watched.Execute(&content, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	user := &trakt.User{Name: "me"}
+	if err := json.Unmarshal(content.Bytes(), &user.WatchedShows); err != nil {
+		t.Fatalf("could not parse %s: %v", path, err)
+	}
+	return user
+}
+
+// describeCleanup describes the decisions and errors of a run and the files that remain in the
+// library, with paths relative to libraryRoot
+func describeCleanup(result *runResult, library *filesystem.Memory) string {
+	relative := func(path string) string {
+		return strings.TrimPrefix(path, libraryRoot+"/")
+	}
+
+	var decisions []string
+	for _, record := range result.Files() {
+		decisions = append(decisions, fmt.Sprintf("%s: %s (%s)", relative(record.Path), record.Action, record.Reason))
+	}
+	for _, fileErr := range result.Errors() {
+		kind := "error"
+		if fileErr.Unrecognized {
+			kind = "unrecognized"
+		}
+		decisions = append(decisions, fmt.Sprintf("%s: %s (%v)", relative(fileErr.Path), kind, fileErr.Err))
+	}
+	sort.Strings(decisions)
+
+	var description strings.Builder
+	description.WriteString("# Decisions\n")
+	for _, line := range decisions {
+		description.WriteString(line + "\n")
+	}
+	description.WriteString("\n# Remaining files\n")
+	for _, path := range library.Files() {
+		description.WriteString(relative(path) + "\n")
+	}
+	return description.String()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCommand() *cobra.Command {
+	command := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+
+	command.AddCommand(
+		&cobra.Command{
+			Use:   "validate",
+			Short: "Validate the configuration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				// The configuration has already been loaded and validated at this point
+				fmt.Fprintln(cmd.OutOrStdout(), "Configuration is valid")
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "print",
+			Short: "Print the effective configuration with secrets redacted",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetEscapeHTML(false)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(config.Current())
+			},
+		},
+		&cobra.Command{
+			Use:   "schema",
+			Short: "Print a JSON Schema of the settings file",
+			Args:  cobra.NoArgs,
+			// The schema does not depend on the configuration, so it can be generated without one
+			PersistentPreRunE: func(*cobra.Command, []string) error {
+				return nil
+			},
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetEscapeHTML(false)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(config.Schema())
+			},
+		},
+	)
+
+	return command
+}
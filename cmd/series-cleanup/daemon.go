@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/bjw-s/series-cleanup/internal/metrics"
+	"github.com/bjw-s/series-cleanup/internal/scrobble"
+	"github.com/bjw-s/series-cleanup/internal/server"
+	"github.com/bjw-s/series-cleanup/internal/webui"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func newDaemonCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "daemon",
+		Short: "Keep running and remove watched episodes on a schedule",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return runDaemon(cmd.Context())
+		},
+	}
+}
+
+func runDaemon(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	status := &daemonStatus{}
+
+	// The HTTP server, watch mode and scrobble webhooks are set up once, using the configuration at startup
+	settings := config.Current()
+	if err := checkScanFoldersWritable(settings); err != nil {
+		return err
+	}
+	if err := watchConfig(ctx); err != nil {
+		return err
+	}
+
+	// scrobbleReady stays nil when the scrobble webhooks are disabled, so receiving from it blocks forever
+	var scheduler *scrobbleScheduler
+	var scrobbleReady <-chan struct{}
+	if settings.Scrobble.Enabled && settings.Server.ListenAddress != "" {
+		scheduler = newScrobbleScheduler()
+		scrobbleReady = scheduler.Ready()
+		if err := scheduler.Restore(); err != nil {
+			return err
+		}
+	}
+
+	// serverErrors stays nil when the HTTP server is disabled, so receiving from it blocks forever
+	var serverErrors chan error
+	if settings.Server.ListenAddress != "" {
+		serverErrors = make(chan error, 1)
+		httpServer := server.New(settings.Server.ListenAddress)
+		httpServer.Handle("/metrics", metrics.Handler())
+		httpServer.HandleFunc("/healthz", status.HandleHealthz)
+		httpServer.HandleFunc("/readyz", status.HandleReadyz)
+		httpServer.HandleFunc("/status", status.HandleStatus)
+		httpServer.HandleFunc("/report", status.HandleReport)
+		if settings.Approval.Enabled {
+			httpServer.Handle("/ui/", http.StripPrefix("/ui", webui.New(withStateStore, uiOverrides{}, string(settings.Approval.Token))))
+		}
+		if scheduler != nil {
+			httpServer.Handle("/scrobble/", http.StripPrefix("/scrobble", scrobble.NewHandler(string(settings.Scrobble.Token), scheduler.Receive)))
+		}
+
+		logger.Info("Starting HTTP server",
+			zap.String("address", settings.Server.ListenAddress),
+		)
+		go func() {
+			serverErrors <- httpServer.Run(ctx)
+		}()
+	}
+
+	// queue stays nil when watch mode is disabled, so every run scans the scan folders completely
+	var queue *episodeQueue
+	if settings.Daemon.Watch {
+		queue = newEpisodeQueue()
+		if err := watchScanFolders(ctx, queue); err != nil {
+			return err
+		}
+		logger.Info("Watching scan folders for new episodes")
+	}
+
+	// run runs a cleanup of the queued files, or of all files when queued is nil
+	run := func(runSettings *config.Settings, queued map[string][]string) {
+		startedAt := time.Now()
+		result, err := runCleanup(ctx, runSettings, queued, allOutputs)
+		status.RunFinished(startedAt, result, err)
+		if queue != nil {
+			queue.RunFinished(runSettings, queued == nil, startedAt, result, err)
+		}
+		if err != nil && ctx.Err() == nil {
+			if errors.Is(err, errPartialFailure) {
+				logger.Error("Finished with errors",
+					zap.Error(err),
+				)
+			} else {
+				logger.Error("Run failed",
+					zap.Error(err),
+				)
+			}
+		}
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Shutting down")
+			if serverErrors == nil {
+				return nil
+			}
+			return <-serverErrors
+		case err := <-serverErrors:
+			return err
+		case <-scrobbleReady:
+			logger.Info("Evaluating watched episodes reported by media servers")
+			run(config.Current(), scheduler.Take())
+			continue
+		case <-timer.C:
+		}
+
+		// The configuration is taken once per run, so a reload does not affect a run in progress
+		runSettings := config.Current()
+		var queued map[string][]string
+		if queue != nil {
+			queued = queue.Scope(runSettings, time.Now())
+			if queued != nil {
+				logger.Info("Evaluating queued episodes",
+					zap.Int("count", queue.Len()),
+				)
+			}
+		}
+		run(runSettings, queued)
+
+		interval := time.Duration(runSettings.Daemon.IntervalMinutes) * time.Minute
+		nextRun := time.Now().Add(interval)
+		status.SetNextRun(nextRun)
+		timer.Reset(interval)
+		logger.Info("Waiting for next run",
+			zap.Time("next", nextRun),
+		)
+	}
+}
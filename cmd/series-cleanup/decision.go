@@ -0,0 +1,40 @@
+package main
+
+// fileAction is the action that was taken for a TV show file
+type fileAction string
+
+const (
+	actionDeleted     fileAction = "deleted"
+	actionWouldDelete fileAction = "would_delete"
+	actionKept        fileAction = "kept"
+	actionSkipped     fileAction = "skipped"
+	// actionPendingApproval is used for files that can be deleted once the deletion is approved
+	actionPendingApproval fileAction = "pending_approval"
+)
+
+const (
+	reasonShowSkipped           = "Show is configured to be skipped"
+	reasonSeasonSkipped         = "Season is configured to be skipped"
+	reasonShowUnwatched         = "Show is unwatched or could not be found"
+	reasonSeasonUnwatched       = "Season is unwatched"
+	reasonEpisodeUnwatched      = "Episode is unwatched"
+	reasonWatchedTooRecently    = "Episode was watched too recently"
+	reasonDownloadedTooRecently = "Episode was downloaded too recently"
+	reasonNotPlayedOftenEnough  = "Episode was not played often enough"
+	reasonWatched               = "Episode was watched"
+	reasonDryRun                = "Dry run"
+	reasonWaitingForApproval    = "Deletion is waiting for approval"
+	reasonDeletionRejected      = "Deletion was rejected"
+	reasonDeletionApproved      = "Episode was watched and the deletion was approved"
+)
+
+// decision describes what was done with a TV show file and why
+type decision struct {
+	Action fileAction
+	Reason string
+}
+
+// IsCandidate indicates if the file was eligible for removal
+func (d decision) IsCandidate() bool {
+	return d.Action == actionDeleted || d.Action == actionWouldDelete || d.Action == actionPendingApproval
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/audit"
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+	"github.com/bjw-s/series-cleanup/internal/state"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCommand() *cobra.Command {
+	var limit int
+	var action string
+	var since string
+	var fromAuditLog bool
+	var outputJSON bool
+
+	historyCommand := &cobra.Command{
+		Use:   "history",
+		Short: "Show what was done with the TV show files in previous runs",
+		Long: "Show what was done with the TV show files in previous runs, as recorded in the state database.\n" +
+			"With --audit, the removed files are read from the audit log instead.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			sinceTime, err := parseSince(since, time.Now())
+			if err != nil {
+				return err
+			}
+
+			if fromAuditLog {
+				entries, err := audit.Read(config.Current().Audit.Path, sinceTime)
+				if err != nil {
+					return fmt.Errorf("could not read audit log: %w", err)
+				}
+				if limit > 0 && len(entries) > limit {
+					entries = entries[len(entries)-limit:]
+				}
+				return printAuditEntries(cmd.OutOrStdout(), entries, outputJSON)
+			}
+
+			if !helpers.FileExists(stateDatabasePath()) {
+				fmt.Fprintln(cmd.OutOrStdout(), "No history has been recorded yet")
+				return nil
+			}
+
+			store, err := state.OpenReadOnly(stateDatabasePath())
+			if err != nil {
+				return fmt.Errorf("could not open state database: %w", err)
+			}
+			defer store.Close()
+
+			events, err := store.Events(sinceTime)
+			if err != nil {
+				return err
+			}
+
+			if action != "" {
+				var filtered []state.Event
+				for _, event := range events {
+					if event.Action == action {
+						filtered = append(filtered, event)
+					}
+				}
+				events = filtered
+			}
+
+			if limit > 0 && len(events) > limit {
+				events = events[len(events)-limit:]
+			}
+			return printStateEvents(cmd.OutOrStdout(), events, outputJSON)
+		},
+	}
+
+	historyCommand.Flags().IntVar(&limit, "limit", 50, "maximum number of entries to show, 0 shows all entries")
+	historyCommand.Flags().StringVar(&action, "action", "", "only show entries with this action (deleted, would_delete, kept, skipped)")
+	historyCommand.Flags().StringVar(&since, "since", "", "only show entries since a duration ago (e.g. 12h, 7d) or a date (e.g. 2023-05-01 or RFC 3339)")
+	historyCommand.Flags().BoolVar(&fromAuditLog, "audit", false, "show the removed files from the audit log")
+	historyCommand.Flags().BoolVar(&outputJSON, "json", false, "print the entries as JSON lines")
+
+	return historyCommand
+}
+
+// parseSince parses a duration before now, which may also be given in days, or an absolute time
+func parseSince(since string, now time.Time) (time.Time, error) {
+	if since == "" {
+		return time.Time{}, nil
+	}
+
+	if strings.HasSuffix(since, "d") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(since, "d")); err == nil {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+
+	if duration, err := time.ParseDuration(since); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, since, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid value for --since: %v", since)
+}
+
+func printStateEvents(out io.Writer, events []state.Event, outputJSON bool) error {
+	if outputJSON {
+		return printJSONLines(out, events)
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tACTION\tSHOW\tEPISODE\tSIZE\tREASON\tFILE")
+	for _, event := range events {
+		fmt.Fprintf(writer, "%v\t%v\t%v\tS%02dE%02d\t%v\t%v\t%v\n",
+			event.Time.Local().Format(time.RFC3339), event.Action, event.Show, event.Season, event.Episode,
+			helpers.FormatBytes(event.Size), event.Reason, event.Path,
+		)
+	}
+	return writer.Flush()
+}
+
+func printAuditEntries(out io.Writer, entries []audit.Entry, outputJSON bool) error {
+	if outputJSON {
+		return printJSONLines(out, entries)
+	}
+
+	writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tSHOW\tEPISODE\tSIZE\tLAST WATCHED\tPOLICY\tFILE")
+	for _, entry := range entries {
+		fmt.Fprintf(writer, "%v\t%v\tS%02dE%02d\t%v\t%v\t%v\t%v\n",
+			entry.Time.Local().Format(time.RFC3339), entry.Show, entry.Season, entry.Episode,
+			helpers.FormatBytes(entry.Size), entry.LastWatched.Local().Format(time.RFC3339),
+			fmt.Sprintf("%v>%dh", entry.Policy.DeleteBasedOn, entry.Policy.DeleteAfterHours), entry.Path,
+		)
+	}
+	return writer.Flush()
+}
+
+func printJSONLines[T any](out io.Writer, values []T) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	for _, value := range values {
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
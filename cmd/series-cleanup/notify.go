@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/bjw-s/series-cleanup/internal/notify"
+	"github.com/bjw-s/series-cleanup/internal/report"
+	"go.uber.org/zap"
+)
+
+// notifyLog logs the notifications that are sent
+var notifyLog = logger.Named("notify")
+
+// notificationTimeout bounds how long sending all notifications for a run may take.
+// Notifications are still sent when the run was interrupted, so they do not use the run context.
+const notificationTimeout = 30 * time.Second
+
+const defaultSMTPPort = 587
+
+var defaultNotificationTriggers = []notify.Trigger{notify.TriggerDeletion, notify.TriggerError}
+
+func newNotificationTargets(settings *config.Settings) ([]notify.Target, error) {
+	var targets []notify.Target
+	for i, notification := range settings.Notifications {
+		var notifier notify.Notifier
+		switch notification.Type {
+		case "webhook":
+			webhook, err := notify.NewWebhook(notification.URL, notification.Method, notification.Headers, notification.Body)
+			if err != nil {
+				return nil, fmt.Errorf("invalid body template for notification %d: %w", i, err)
+			}
+			notifier = webhook
+		case "ntfy":
+			notifier = &notify.Ntfy{
+				URL:      notification.URL,
+				Topic:    notification.Topic,
+				Token:    string(notification.Token),
+				Priority: notification.Priority,
+			}
+		case "gotify":
+			notifier = &notify.Gotify{
+				URL:      notification.URL,
+				Token:    string(notification.Token),
+				Priority: notification.Priority,
+			}
+		case "discord":
+			notifier = &notify.Chat{URL: notification.URL, Style: notify.ChatStyleDiscord}
+		case "slack":
+			notifier = &notify.Chat{URL: notification.URL, Style: notify.ChatStyleSlack}
+		case "smtp":
+			port := notification.SMTP.Port
+			if port == 0 {
+				port = defaultSMTPPort
+			}
+			notifier = &notify.SMTP{
+				Host:     notification.SMTP.Host,
+				Port:     port,
+				Username: notification.SMTP.Username,
+				Password: string(notification.SMTP.Password),
+				From:     notification.SMTP.From,
+				To:       notification.SMTP.To,
+			}
+		default:
+			return nil, fmt.Errorf("unknown notification type: %v", notification.Type)
+		}
+
+		triggers := defaultNotificationTriggers
+		if len(notification.Triggers) > 0 {
+			triggers = nil
+			for _, trigger := range notification.Triggers {
+				triggers = append(triggers, notify.Trigger(trigger))
+			}
+		}
+
+		targets = append(targets, notify.Target{
+			Name:     fmt.Sprintf("%v[%d]", notification.Type, i),
+			Notifier: notifier,
+			Triggers: triggers,
+		})
+	}
+	return targets, nil
+}
+
+func newNotificationRun(runReport report.Report) notify.Run {
+	run := notify.Run{
+		StartedAt:  runReport.StartedAt,
+		FinishedAt: runReport.FinishedAt,
+		DryRun:     runReport.DryRun,
+		Error:      runReport.Error,
+	}
+
+	for _, file := range runReport.Files {
+		if file.Action != string(actionDeleted) && file.Action != string(actionWouldDelete) {
+			continue
+		}
+		run.Removed = append(run.Removed, notify.File{
+			Path:    file.Path,
+			Show:    file.Show,
+			Season:  file.Season,
+			Episode: file.Episode,
+			Size:    file.Size,
+		})
+		run.BytesFreed += file.Size
+	}
+
+	for _, failure := range append(runReport.Unrecognized, runReport.Failed...) {
+		run.Failures = append(run.Failures, notify.Failure{
+			Path:  failure.Path,
+			Error: failure.Error,
+		})
+	}
+
+	return run
+}
+
+// newReportAttachment renders the report in the format with the given name, HTML by default
+func newReportAttachment(runReport report.Report, formatName string) (*notify.Attachment, error) {
+	format := report.FormatHTML
+	if formatName != "" {
+		format = report.Format(formatName)
+	}
+
+	content, err := runReport.String(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notify.Attachment{
+		Filename:    reportFilename(runReport, format),
+		ContentType: format.ContentType(),
+		Content:     content,
+	}, nil
+}
+
+// sendNotifications notifies all configured targets whose triggers apply to the run.
+// Failing notifications are logged, but do not fail the run.
+func sendNotifications(settings *config.Settings, runReport report.Report) {
+	if len(settings.Notifications) == 0 {
+		return
+	}
+
+	targets, err := newNotificationTargets(settings)
+	if err != nil {
+		notifyLog.Error("Could not configure notifications",
+			zap.Error(err),
+		)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	run := newNotificationRun(runReport)
+	for i, target := range targets {
+		if !target.ShouldNotify(run) {
+			continue
+		}
+
+		targetRun := run
+		if notification := settings.Notifications[i]; notification.AttachReport {
+			attachment, err := newReportAttachment(runReport, notification.ReportFormat)
+			if err != nil {
+				notifyLog.Error("Could not render report",
+					zap.String("notification", target.Name),
+					zap.Error(err),
+				)
+			}
+			targetRun.Report = attachment
+		}
+
+		if err := target.Notifier.Notify(ctx, targetRun); err != nil {
+			notifyLog.Error("Could not send notification",
+				zap.String("notification", target.Name),
+				zap.Error(err),
+			)
+			continue
+		}
+		notifyLog.Debug("Sent notification",
+			zap.String("notification", target.Name),
+		)
+	}
+}
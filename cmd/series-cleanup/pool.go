@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bjw-s/series-cleanup/internal/mediafile"
+)
+
+// forEachTvShowFile calls process for every file using at most concurrency goroutines.
+// No new files are scheduled once ctx is done, but files that are already being
+// processed are allowed to finish before forEachTvShowFile returns.
+func forEachTvShowFile(ctx context.Context, files []*mediafile.TVShowFile, concurrency int, process func(*mediafile.TVShowFile)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	jobs := make(chan *mediafile.TVShowFile)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				process(file)
+			}
+		}()
+	}
+
+	defer wg.Wait()
+	defer close(jobs)
+
+	for _, file := range files {
+		// Check for cancellation first, so a cancelled context always wins over an idle worker
+		if ctx.Err() != nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case jobs <- file:
+		}
+	}
+}
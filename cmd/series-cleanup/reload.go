@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// configLog logs the reloading of the configuration
+var configLog = logger.Named("config")
+
+// reloadDelay is how long to wait after the settings file changed before it is reloaded,
+// as editors often write a file in several steps
+const reloadDelay = time.Second
+
+// restartRequiredKeys are the configuration keys, or prefixes of keys, that only take effect
+// when the daemon is restarted
+var restartRequiredKeys = []string{
+	"approval.",
+	"daemon.watch",
+	"scrobble.",
+	"server.",
+}
+
+// watchConfig reloads the configuration when the settings file changes or SIGHUP is received,
+// until ctx is cancelled
+func watchConfig(ctx context.Context) error {
+	settingsPath, err := config.SettingsPath(configFolder, settingsFile)
+	if err != nil {
+		return err
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not watch configuration: %w", err)
+	}
+	// The folder is watched instead of the file, as editors and Kubernetes replace the file
+	// instead of writing to it
+	if err := fsWatcher.Add(filepath.Dir(settingsPath)); err != nil {
+		fsWatcher.Close()
+		return fmt.Errorf("could not watch configuration: %w", err)
+	}
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+
+	go func() {
+		defer fsWatcher.Close()
+		defer signal.Stop(hangup)
+
+		// reload stays nil until the settings file changed, so receiving from it blocks
+		var reload <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hangup:
+				configLog.Info("Received SIGHUP, reloading configuration")
+				reloadConfig()
+			case event := <-fsWatcher.Events:
+				name := filepath.Base(event.Name)
+				// Kubernetes updates mounted config maps by replacing the ..data symlink
+				if name == filepath.Base(settingsPath) || strings.HasPrefix(name, "..data") {
+					reload = time.After(reloadDelay)
+				}
+			case err := <-fsWatcher.Errors:
+				configLog.Error("Error while watching configuration",
+					zap.Error(err),
+				)
+			case <-reload:
+				reload = nil
+				configLog.Info("Settings file changed, reloading configuration")
+				reloadConfig()
+			}
+		}
+	}()
+	return nil
+}
+
+// reloadConfig reads and validates the configuration and makes it the active configuration.
+// When it is invalid, the active configuration is kept.
+func reloadConfig() {
+	settings, err := config.Read(configFolder, settingsFile)
+	if err == nil {
+		err = checkScanFoldersWritable(settings)
+	}
+	if err != nil {
+		configLog.Error("Could not reload configuration, keeping the active configuration",
+			zap.Error(err),
+		)
+		return
+	}
+
+	changes := config.Diff(config.Current(), settings)
+	if len(changes) == 0 {
+		configLog.Info("Configuration is unchanged")
+		return
+	}
+
+	if err := configureLogger(settings); err != nil {
+		configLog.Error("Could not reload configuration, keeping the active configuration",
+			zap.Error(err),
+		)
+		return
+	}
+	config.Set(settings)
+
+	for _, change := range changes {
+		configLog.Info("Configuration changed",
+			zap.String("key", change.Key),
+			zap.Any("old", change.Old),
+			zap.Any("new", change.New),
+		)
+		if requiresRestart(change.Key) {
+			configLog.Error("Configuration change only takes effect after a restart",
+				zap.String("key", change.Key),
+			)
+		}
+	}
+}
+
+func requiresRestart(key string) bool {
+	for _, restartKey := range restartRequiredKeys {
+		if key == restartKey || (strings.HasSuffix(restartKey, ".") && strings.HasPrefix(key, restartKey)) {
+			return true
+		}
+	}
+	// The watched folders are set up when the daemon starts
+	return strings.HasPrefix(key, "scanFolders") && config.Current().Daemon.Watch
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/bjw-s/series-cleanup/internal/report"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// addReportFlag adds the flag to print the run report to stdout to cmd
+func addReportFlag(cmd *cobra.Command, format *string) {
+	cmd.Flags().StringVar(format, "report", "", "print a report of the run to stdout (text, markdown or html)")
+}
+
+func newRunReport(settings *config.Settings, startedAt time.Time, result *runResult, runErr error) report.Report {
+	runReport := report.Report{
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+		DryRun:     settings.DryRun,
+	}
+
+	for _, file := range result.Files() {
+		runReport.Files = append(runReport.Files, report.File{
+			Path:    file.Path,
+			Show:    file.Show,
+			Season:  file.Season,
+			Episode: file.Episode,
+			Size:    file.Size,
+			Action:  string(file.Action),
+			Reason:  file.Reason,
+		})
+	}
+
+	for _, fileErr := range result.Errors() {
+		failure := report.Failure{Path: fileErr.Path, Error: fileErr.Err.Error()}
+		if fileErr.Unrecognized {
+			runReport.Unrecognized = append(runReport.Unrecognized, failure)
+		} else {
+			runReport.Failed = append(runReport.Failed, failure)
+		}
+	}
+
+	if runErr != nil && !errors.Is(runErr, errPartialFailure) {
+		runReport.Error = runErr.Error()
+	}
+
+	return runReport
+}
+
+// writeReportFile writes the report to report.path, replacing the report of the previous run.
+// Failures are logged, as the run itself has already finished at this point.
+func writeReportFile(settings *config.Settings, runReport report.Report) {
+	if settings.Report.Path == "" {
+		return
+	}
+
+	format := report.FormatFromPath(settings.Report.Path)
+	if settings.Report.Format != "" {
+		format = report.Format(settings.Report.Format)
+	}
+
+	if err := writeFileAtomically(settings.Report.Path, func(file *os.File) error {
+		return runReport.Render(file, format)
+	}); err != nil {
+		logger.Error("Could not write report",
+			zap.String("path", settings.Report.Path),
+			zap.Error(err),
+		)
+	}
+}
+
+// writeFileAtomically writes a temporary file next to path using write and renames it to path,
+// so readers never see a partially written file
+func writeFileAtomically(path string, write func(file *os.File) error) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func printReport(cmd *cobra.Command, runReport report.Report, formatName string) error {
+	format, err := report.ParseFormat(formatName)
+	if err != nil {
+		return err
+	}
+	return runReport.Render(cmd.OutOrStdout(), format)
+}
+
+func reportFilename(runReport report.Report, format report.Format) string {
+	return fmt.Sprintf("series-cleanup-%v%v", runReport.StartedAt.Format("20060102-150405"), format.Extension())
+}
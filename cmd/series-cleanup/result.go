@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/mediafile"
+	"github.com/bjw-s/series-cleanup/internal/metrics"
+	"github.com/bjw-s/series-cleanup/internal/report"
+)
+
+const (
+	exitCodeFatal          = 1
+	exitCodePartialFailure = 2
+)
+
+// errPartialFailure is returned when a run completed, but one or more files could not be processed
+var errPartialFailure = errors.New("one or more files could not be processed")
+
+// fileError describes why a single file could not be processed
+type fileError struct {
+	Path string `json:"path"`
+	Err  error  `json:"-"`
+	// Unrecognized indicates that the file could not be recognized as a TV show episode
+	Unrecognized bool `json:"unrecognized"`
+}
+
+func (e fileError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Path, e.Err)
+}
+
+func (e fileError) Unwrap() error {
+	return e.Err
+}
+
+// runSummary contains the totals of a cleanup run
+type runSummary struct {
+	Processed   int `json:"processed"`
+	Deleted     int `json:"deleted"`
+	WouldDelete int `json:"wouldDelete"`
+	Kept        int `json:"kept"`
+	Skipped     int `json:"skipped"`
+	Pending     int `json:"pendingApproval"`
+	Failed      int `json:"failed"`
+	// Unrecognized files are reported, but do not count as failures
+	Unrecognized int   `json:"unrecognized"`
+	BytesFreed   int64 `json:"bytesFreed"`
+}
+
+// fileRecord describes what was done with a single TV show file
+type fileRecord struct {
+	ScanFolder string     `json:"scanFolder"`
+	Path       string     `json:"path"`
+	Show       string     `json:"show"`
+	Season     int        `json:"season"`
+	Episode    int        `json:"episode"`
+	Size       int64      `json:"size"`
+	Action     fileAction `json:"action"`
+	Reason     string     `json:"reason"`
+	DecidedAt  time.Time  `json:"decidedAt"`
+}
+
+// runResult contains the outcome of a cleanup run.
+// It is safe for concurrent use.
+type runResult struct {
+	mutex   sync.Mutex
+	summary runSummary
+	files   []fileRecord
+	errors  []fileError
+
+	// traktTokenExpiresAt is when the Trakt access token used for the run expires
+	traktTokenExpiresAt time.Time
+	// watchedShowsSyncedAt is when the watched shows were retrieved for the run
+	watchedShowsSyncedAt time.Time
+	// scanFoldersReachable tells for each scan folder the run tried to open if that succeeded
+	scanFoldersReachable map[string]bool
+	// report is the report of the finished run
+	report report.Report
+}
+
+// SetTraktTokenExpiry registers when the Trakt access token used for the run expires
+func (result *runResult) SetTraktTokenExpiry(expiresAt time.Time) {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	result.traktTokenExpiresAt = expiresAt
+}
+
+// SetWatchedShowsSynced registers that the watched shows were retrieved successfully
+func (result *runResult) SetWatchedShowsSynced(syncedAt time.Time) {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	result.watchedShowsSyncedAt = syncedAt
+}
+
+// SetScanFolderReachable registers if a scan folder could be opened
+func (result *runResult) SetScanFolderReachable(scanFolder string, reachable bool) {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	if result.scanFoldersReachable == nil {
+		result.scanFoldersReachable = map[string]bool{}
+	}
+	result.scanFoldersReachable[scanFolder] = reachable
+}
+
+// AddDecision registers what was done with a file that was processed successfully
+func (result *runResult) AddDecision(scanFolder string, file *mediafile.TVShowFile, d decision) {
+	metrics.FileAction(scanFolder, string(d.Action), d.Reason)
+	if d.Action == actionDeleted {
+		metrics.BytesFreed(scanFolder, file.Size())
+	}
+
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+
+	result.files = append(result.files, fileRecord{
+		ScanFolder: scanFolder,
+		Path:       filepath.Join(file.Dir, file.Filename),
+		Show:       file.Show,
+		Season:     file.Season,
+		Episode:    file.Episode,
+		Size:       file.Size(),
+		Action:     d.Action,
+		Reason:     d.Reason,
+		DecidedAt:  time.Now(),
+	})
+
+	result.summary.Processed++
+	switch d.Action {
+	case actionDeleted:
+		result.summary.Deleted++
+		result.summary.BytesFreed += file.Size()
+	case actionWouldDelete:
+		result.summary.WouldDelete++
+	case actionKept:
+		result.summary.Kept++
+	case actionSkipped:
+		result.summary.Skipped++
+	case actionPendingApproval:
+		result.summary.Pending++
+	}
+}
+
+// AddError registers that a file could not be processed
+func (result *runResult) AddError(path string, err error) {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	result.summary.Failed++
+	result.errors = append(result.errors, fileError{Path: path, Err: err})
+}
+
+// AddUnrecognized registers that a media file could not be recognized as a TV show episode
+func (result *runResult) AddUnrecognized(path string, err error) {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	result.summary.Unrecognized++
+	result.errors = append(result.errors, fileError{Path: path, Err: err, Unrecognized: true})
+}
+
+// AddFileError registers that a TV show file could not be processed
+func (result *runResult) AddFileError(file *mediafile.TVShowFile, err error) {
+	result.AddError(filepath.Join(file.Dir, file.Filename), err)
+}
+
+// TraktTokenExpiry returns when the Trakt access token used for the run expires
+func (result *runResult) TraktTokenExpiry() time.Time {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	return result.traktTokenExpiresAt
+}
+
+// WatchedShowsSyncedAt returns when the watched shows were retrieved, or the zero time if that failed
+func (result *runResult) WatchedShowsSyncedAt() time.Time {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	return result.watchedShowsSyncedAt
+}
+
+// ScanFoldersReachable returns for each scan folder the run tried to open if that succeeded
+func (result *runResult) ScanFoldersReachable() map[string]bool {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	reachable := make(map[string]bool, len(result.scanFoldersReachable))
+	for scanFolder, ok := range result.scanFoldersReachable {
+		reachable[scanFolder] = ok
+	}
+	return reachable
+}
+
+// SetReport registers the report of the finished run
+func (result *runResult) SetReport(runReport report.Report) {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	result.report = runReport
+}
+
+// Report returns the report of the finished run
+func (result *runResult) Report() report.Report {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	return result.report
+}
+
+// Summary returns the totals of the run so far
+func (result *runResult) Summary() runSummary {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	return result.summary
+}
+
+// Files returns what was done with every file that was processed successfully
+func (result *runResult) Files() []fileRecord {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	return append([]fileRecord(nil), result.files...)
+}
+
+// Errors returns the errors for all files that could not be processed or recognized
+func (result *runResult) Errors() []fileError {
+	result.mutex.Lock()
+	defer result.mutex.Unlock()
+	return append([]fileError(nil), result.errors...)
+}
+
+// failures returns the errors for the files that could not be processed, leaving out the
+// files that could not be recognized
+func (result *runResult) failures() []fileError {
+	var failures []fileError
+	for _, fileErr := range result.Errors() {
+		if !fileErr.Unrecognized {
+			failures = append(failures, fileErr)
+		}
+	}
+	return failures
+}
+
+// HasErrors indicates if any file could not be processed
+func (result *runResult) HasErrors() bool {
+	return len(result.failures()) > 0
+}
+
+// Err returns errPartialFailure, wrapping the individual file errors, if any file could not be processed
+func (result *runResult) Err() error {
+	failures := result.failures()
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d failed, first error: %v", errPartialFailure, len(failures), failures[0])
+}
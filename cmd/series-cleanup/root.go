@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	configFolder string
+	// settingsFile is the path of the settings file, it is looked for in configFolder when empty
+	settingsFile string
+)
+
+func newRootCommand() *cobra.Command {
+	rootCommand := &cobra.Command{
+		Use:   "series-cleanup",
+		Short: "Removes TV show episodes that have been watched on Trakt.tv",
+		Long: "series-cleanup searches the configured folders for TV shows and removes episodes that are marked as watched on Trakt.tv.\n" +
+			"When no command is given, the run command is executed.",
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		PersistentPreRunE: loadConfig,
+		RunE:              runCleanupCommand,
+	}
+
+	addReportFlag(rootCommand, &reportFormat)
+	rootCommand.PersistentFlags().StringVar(&configFolder, "configFolder", "/config", "path to store the configuration")
+	rootCommand.PersistentFlags().StringVar(&settingsFile, "config", "", "path of the settings file (default settings.json, settings.yaml or settings.toml in the configuration folder)")
+
+	rootCommand.AddCommand(
+		newRunCommand(),
+		newDaemonCommand(),
+		newPlanCommand(),
+		newAuthCommand(),
+		newWhoamiCommand(),
+		newConfigCommand(),
+		newListWatchedCommand(),
+		newScanCommand(),
+		newHistoryCommand(),
+	)
+
+	return rootCommand
+}
+
+func loadConfig(_ *cobra.Command, _ []string) error {
+	if err := config.Load(configFolder, settingsFile); err != nil {
+		return err
+	}
+
+	if err := configureLogger(config.Current()); err != nil {
+		return err
+	}
+
+	logger.Debug("Loaded configuration",
+		zap.Any("configuration", config.Current()),
+	)
+	return nil
+}
+
+// configureLogger configures the log output according to settings
+func configureLogger(settings *config.Settings) error {
+	return logger.Configure(logger.Options{
+		Format:     settings.Log.Format,
+		Level:      settings.LogLevel,
+		Levels:     settings.Log.Levels,
+		File:       settings.Log.Path,
+		MaxSizeMB:  settings.Log.MaxSizeMB,
+		MaxBackups: settings.Log.MaxBackups,
+	})
+}
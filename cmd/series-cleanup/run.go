@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/audit"
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/bjw-s/series-cleanup/internal/mediafile"
+	"github.com/bjw-s/series-cleanup/internal/metrics"
+	"github.com/bjw-s/series-cleanup/internal/report"
+	"github.com/bjw-s/series-cleanup/internal/state"
+	"github.com/bjw-s/series-cleanup/internal/trakt"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// reportFormat is the format of the report printed by the run and plan commands, empty when disabled
+var reportFormat string
+
+// runOutputs selects what a cleanup publishes besides its result
+type runOutputs struct {
+	// notify sends the configured notifications
+	notify bool
+	// reportFile writes the report to report.path
+	reportFile bool
+}
+
+// allOutputs are the outputs of the run command and the daemon
+var allOutputs = runOutputs{notify: true, reportFile: true}
+
+// A run reaches Trakt and the scan folders through these variables, so tests can run a cleanup
+// against stand-ins
+var (
+	runAuthenticateWithTrakt = authenticateWithTrakt
+	runGetTraktUser          = getTraktUser
+	runOpenScanFolder        = openScanFolder
+)
+
+func newRunCommand() *cobra.Command {
+	runCommand := &cobra.Command{
+		Use:   "run",
+		Short: "Remove watched episodes from the scan folders",
+		Args:  cobra.NoArgs,
+		RunE:  runCleanupCommand,
+	}
+	addReportFlag(runCommand, &reportFormat)
+	return runCommand
+}
+
+func newPlanCommand() *cobra.Command {
+	planCommand := &cobra.Command{
+		Use:   "plan",
+		Short: "Show which episodes would be removed without removing them",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			settings := *config.Current()
+			settings.DryRun = true
+			config.Set(&settings)
+			// A plan is only printed, it is not reported as a run
+			return runCleanupAndReport(cmd, runOutputs{})
+		},
+	}
+	addReportFlag(planCommand, &reportFormat)
+	return planCommand
+}
+
+func runCleanupCommand(cmd *cobra.Command, _ []string) error {
+	if err := checkScanFoldersWritable(config.Current()); err != nil {
+		return err
+	}
+	return runCleanupAndReport(cmd, allOutputs)
+}
+
+// checkScanFoldersWritable checks that files can be removed from the scan folders, unless the
+// configuration is a dry run
+func checkScanFoldersWritable(settings *config.Settings) error {
+	if settings.DryRun {
+		return nil
+	}
+	return settings.CheckScanFoldersWritable()
+}
+
+// runCleanupAndReport runs a single cleanup and prints its report when the report flag is set
+func runCleanupAndReport(cmd *cobra.Command, outputs runOutputs) error {
+	if reportFormat != "" {
+		if _, err := report.ParseFormat(reportFormat); err != nil {
+			return err
+		}
+	}
+
+	result, err := runCleanup(cmd.Context(), config.Current(), nil, outputs)
+	if reportFormat != "" {
+		if reportErr := printReport(cmd, result.Report(), reportFormat); reportErr != nil {
+			return reportErr
+		}
+	}
+	return err
+}
+
+// runCleanup runs a single cleanup of all scan folders, records its duration and outcome,
+// and writes the report and sends the configured notifications as selected by outputs.
+// The whole run uses settings, even if the configuration is reloaded in the meantime.
+// When queued is not nil, only the queued files of each scan folder are processed instead of all files.
+func runCleanup(ctx context.Context, settings *config.Settings, queued map[string][]string, outputs runOutputs) (*runResult, error) {
+	start := time.Now()
+	result, err := cleanupScanFolders(ctx, settings, queued)
+	metrics.RunFinished(time.Since(start), err == nil)
+
+	runReport := newRunReport(settings, start, result, err)
+	result.SetReport(runReport)
+	if outputs.reportFile {
+		writeReportFile(settings, runReport)
+	}
+	if outputs.notify {
+		sendNotifications(settings, runReport)
+	}
+	return result, err
+}
+
+func cleanupScanFolders(ctx context.Context, settings *config.Settings, queued map[string][]string) (*runResult, error) {
+	result := &runResult{}
+	cleaner := &episodeCleaner{settings: settings}
+
+	// The pending approvals are only replaced when all files were processed,
+	// otherwise the files that were not processed would lose their pending approval.
+	// Dry runs never reach the approval decision, so they leave the approvals alone.
+	completed := false
+	defer func() {
+		err := withStateStore(func(store *state.Store) error {
+			if err := recordDecisions(store, result); err != nil {
+				return err
+			}
+			if settings.Approval.Enabled && !settings.DryRun && completed {
+				return store.SetPendingApprovals(cleaner.PendingApprovals(), queuedPaths(queued))
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Error("Could not record the run in the state database",
+				zap.Error(err),
+			)
+		}
+	}()
+
+	err := withStateStore(func(store *state.Store) error {
+		return cleaner.loadState(store)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	traktAPI, err := runAuthenticateWithTrakt(ctx, settings)
+	if err != nil {
+		return result, err
+	}
+	result.SetTraktTokenExpiry(traktAPI.TokenExpiresAt)
+
+	traktUser, err := runGetTraktUser(ctx, settings, traktAPI)
+	if err != nil {
+		return result, err
+	}
+	if !traktUser.Offline {
+		result.SetWatchedShowsSynced(time.Now())
+	}
+	if settings.Scrobble.Enabled {
+		if err := addScrobbledEpisodes(traktUser); err != nil {
+			return result, err
+		}
+	}
+
+	cleaner.user = traktUser
+	if settings.Matching.TraktSearch {
+		cleaner.resolver, err = trakt.NewShowResolver(traktAPI)
+		if err != nil {
+			return result, fmt.Errorf("could not initialize Trakt show search: %w", err)
+		}
+	}
+
+	if settings.Audit.Enabled && !settings.DryRun {
+		cleaner.auditLog, err = audit.Open(settings.Audit.Path, int64(settings.Audit.MaxSizeMB)*1024*1024, settings.Audit.MaxBackups)
+		if err != nil {
+			return result, fmt.Errorf("could not open audit log: %w", err)
+		}
+		defer cleaner.auditLog.Close()
+	}
+
+	// Processing stops early either on shutdown or, with failFast, on the first failed file
+	processCtx, cancelProcessing := context.WithCancel(ctx)
+	defer cancelProcessing()
+
+	for _, scanFolder := range settings.ScanFolders {
+		logger.Info("Processing...",
+			zap.String("folder", scanFolder),
+		)
+
+		// The file system stays open until the run is finished, the files in it are removed later
+		fileSystem, root, err := runOpenScanFolder(settings, scanFolder)
+		result.SetScanFolderReachable(scanFolder, err == nil)
+		if err != nil {
+			return result, err
+		}
+		defer fileSystem.Close()
+
+		var tvShowFiles []*mediafile.TVShowFile
+		if queued == nil {
+			tvShowFiles, err = collectTvShowFiles(processCtx, settings, fileSystem, scanFolder, root, result)
+		} else {
+			tvShowFiles, err = collectQueuedTvShowFiles(processCtx, settings, fileSystem, scanFolder, queued[scanFolder], result)
+		}
+		if err != nil {
+			if processCtx.Err() != nil {
+				break
+			}
+			return result, fmt.Errorf("could not collect TV show files: %w", err)
+		}
+
+		if settings.FailFast && result.HasErrors() {
+			cancelProcessing()
+			break
+		}
+
+		var firstSeen map[string]time.Time
+		err = withStateStore(func(store *state.Store) (err error) {
+			firstSeen, err = seeTvShowFiles(store, scanFolder, tvShowFiles)
+			return err
+		})
+		if err != nil {
+			return result, fmt.Errorf("could not record TV show files in the state database: %w", err)
+		}
+
+		scanFolder := scanFolder
+		forEachTvShowFile(processCtx, tvShowFiles, settings.Concurrency, func(file *mediafile.TVShowFile) {
+			fileDecision, err := cleaner.processTvShowFile(processCtx, file, firstSeen[filepath.Join(file.Dir, file.Filename)])
+			if fileDecision.IsCandidate() {
+				metrics.Candidate(scanFolder)
+			}
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					return
+				}
+				logger.Error("Could not process TV show file",
+					zap.String("file", file.Filename),
+					zap.Error(err),
+				)
+				result.AddFileError(file, err)
+				if settings.FailFast {
+					cancelProcessing()
+				}
+				return
+			}
+			result.AddDecision(scanFolder, file, fileDecision)
+		})
+
+		if processCtx.Err() != nil {
+			break
+		}
+	}
+
+	summary := result.Summary()
+	if ctx.Err() != nil {
+		logger.Info("Stopped before all files were processed",
+			zap.Any("summary", summary),
+		)
+		return result, fmt.Errorf("run was interrupted: %w", ctx.Err())
+	}
+
+	completed = processCtx.Err() == nil
+	logger.Info("Finished...",
+		zap.Any("summary", summary),
+	)
+	return result, result.Err()
+}
+
+// queuedPaths returns the set of queued paths, or nil when all files are processed
+func queuedPaths(queued map[string][]string) map[string]bool {
+	if queued == nil {
+		return nil
+	}
+	paths := map[string]bool{}
+	for _, scanFolderPaths := range queued {
+		for _, path := range scanFolderPaths {
+			paths[path] = true
+		}
+	}
+	return paths
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/mediafile"
+	"github.com/spf13/cobra"
+)
+
+func newScanCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "scan",
+		Short: "Show how the files in the scan folders are recognized, without contacting Trakt",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			result := &runResult{}
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "SHOW\tSEASON\tEPISODE\tMAPPING\tFILE")
+
+			for _, scanFolder := range config.Current().ScanFolders {
+				fileSystem, root, err := openScanFolder(config.Current(), scanFolder)
+				if err != nil {
+					return err
+				}
+				defer fileSystem.Close()
+
+				tvShowFiles, err := collectTvShowFiles(cmd.Context(), config.Current(), fileSystem, scanFolder, root, result)
+				if err != nil {
+					return fmt.Errorf("could not collect TV show files: %w", err)
+				}
+
+				for _, file := range tvShowFiles {
+					fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\n",
+						file.Show, file.Season, file.Episode, describeMapping(file.Mappings), filepath.Join(file.Dir, file.Filename),
+					)
+				}
+			}
+
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			return result.Err()
+		},
+	}
+}
+
+func describeMapping(mapping mediafile.ShowMapping) string {
+	switch {
+	case mapping.TraktID != 0:
+		return fmt.Sprintf("trakt:%v", mapping.TraktID)
+	case mapping.TraktSlug != "":
+		return fmt.Sprintf("slug:%v", mapping.TraktSlug)
+	case mapping.IMDBID != "":
+		return fmt.Sprintf("imdb:%v", mapping.IMDBID)
+	case mapping.TVDBID != 0:
+		return fmt.Sprintf("tvdb:%v", mapping.TVDBID)
+	case mapping.TMDBID != 0:
+		return fmt.Sprintf("tmdb:%v", mapping.TMDBID)
+	case mapping.TraktName != "":
+		return fmt.Sprintf("name:%v", mapping.TraktName)
+	default:
+		return "-"
+	}
+}
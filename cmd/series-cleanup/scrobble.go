@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/bjw-s/series-cleanup/internal/scrobble"
+	"github.com/bjw-s/series-cleanup/internal/state"
+	"github.com/bjw-s/series-cleanup/internal/trakt"
+	"go.uber.org/zap"
+)
+
+// scrobbleLog logs the watched episodes reported by media servers
+var scrobbleLog = logger.Named("scrobble")
+
+// scrobbleRetention is how long watched episodes reported by media servers are merged into the
+// watched shows from Trakt. Media servers that also scrobble to Trakt have synced them long before.
+const scrobbleRetention = 30 * 24 * time.Hour
+
+// scrobbleDelay is added to when the deletion of a watched episode is due, so it was watched
+// more than deleteAfterHours ago when it is evaluated
+const scrobbleDelay = time.Minute
+
+// scrobbleScheduler schedules the evaluation of the files of episodes that were reported as watched
+// by a media server, once they were watched deleteAfterHours ago. It is safe for concurrent use.
+type scrobbleScheduler struct {
+	mutex sync.Mutex
+	// due maps the paths of the files that are due to their scan folder
+	due   map[string]string
+	ready chan struct{}
+}
+
+func newScrobbleScheduler() *scrobbleScheduler {
+	return &scrobbleScheduler{
+		due:   map[string]string{},
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// Ready receives a value when files are due to be evaluated
+func (scheduler *scrobbleScheduler) Ready() <-chan struct{} {
+	return scheduler.ready
+}
+
+// Take returns the paths of the files that are due by scan folder and clears them
+func (scheduler *scrobbleScheduler) Take() map[string][]string {
+	scheduler.mutex.Lock()
+	defer scheduler.mutex.Unlock()
+
+	scope := map[string][]string{}
+	for path, scanFolder := range scheduler.due {
+		scope[scanFolder] = append(scope[scanFolder], path)
+	}
+	for _, paths := range scope {
+		sort.Strings(paths)
+	}
+	scheduler.due = map[string]string{}
+	return scope
+}
+
+// Schedule makes the files at paths due at dueAt, paths outside of the scan folders of settings are ignored
+func (scheduler *scrobbleScheduler) Schedule(settings *config.Settings, paths []string, dueAt time.Time) {
+	time.AfterFunc(time.Until(dueAt), func() {
+		scheduler.mutex.Lock()
+		for _, path := range paths {
+			if scanFolder := scanFolderOf(settings, path); scanFolder != "" {
+				scheduler.due[path] = scanFolder
+			}
+		}
+		scheduler.mutex.Unlock()
+
+		select {
+		case scheduler.ready <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// Restore schedules the files of the episodes that were reported as watched before the daemon
+// was started, and are not due yet
+func (scheduler *scrobbleScheduler) Restore() error {
+	settings := config.Current()
+	now := time.Now()
+	var scrobbles []state.Scrobble
+	err := withStateStore(func(store *state.Store) (err error) {
+		scrobbles, err = store.Scrobbles(now.Add(-scrobbleRetention))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("could not load watched episodes reported by media servers: %w", err)
+	}
+
+	for _, watched := range scrobbles {
+		if dueAt := scrobbleDueAt(settings, watched.Time); dueAt.After(now) {
+			scheduler.Schedule(settings, watched.Paths, dueAt)
+		}
+	}
+	return nil
+}
+
+// Receive registers an episode that was reported as watched by a media server and schedules
+// the evaluation of its files
+func (scheduler *scrobbleScheduler) Receive(_ context.Context, event scrobble.Event) error {
+	settings := config.Current()
+	watched := state.Scrobble{
+		Time:    event.WatchedAt,
+		Source:  string(event.Source),
+		Show:    event.Show,
+		Season:  event.Season,
+		Episode: event.Episode,
+		User:    event.User,
+	}
+
+	err := withStateStore(func(store *state.Store) error {
+		files, err := store.Files()
+		if err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if file.DeletedAt == nil && scrobbleMatchesFile(settings, event, file) {
+				watched.Paths = append(watched.Paths, file.Path)
+				// The generic webhook can identify the episode by its path only
+				if watched.Show == "" {
+					watched.Show = file.Show
+					watched.Season = file.Season
+					watched.Episode = file.Episode
+				}
+			}
+		}
+
+		if watched.Show == "" {
+			return nil
+		}
+		return store.AddScrobble(watched, time.Now().Add(-scrobbleRetention))
+	})
+	if err != nil {
+		return fmt.Errorf("could not register watched episode: %w", err)
+	}
+
+	if len(watched.Paths) == 0 {
+		scrobbleLog.Info("No TV show file found for watched episode",
+			zap.String("source", watched.Source),
+			zap.String("show", event.Show),
+			zap.Int("season", event.Season),
+			zap.Int("episode", event.Episode),
+			zap.String("path", event.Path),
+		)
+		return nil
+	}
+
+	dueAt := scrobbleDueAt(settings, watched.Time)
+	scrobbleLog.Info("Scheduled evaluation of watched episode",
+		zap.String("source", watched.Source),
+		zap.String("show", watched.Show),
+		zap.Int("season", watched.Season),
+		zap.Int("episode", watched.Episode),
+		zap.Strings("files", watched.Paths),
+		zap.Time("due", dueAt),
+	)
+	scheduler.Schedule(settings, watched.Paths, dueAt)
+	return nil
+}
+
+// scrobbleDueAt returns when an episode that was watched at watchedAt can be deleted
+func scrobbleDueAt(settings *config.Settings, watchedAt time.Time) time.Time {
+	return watchedAt.Add(time.Duration(settings.DeleteAfterHours)*time.Hour + scrobbleDelay)
+}
+
+// scrobbleMatchesFile indicates if a watched episode reported by a media server is stored in file.
+// The show matches the name of the show folder, or the Trakt name it is mapped to by the overrides of settings.
+func scrobbleMatchesFile(settings *config.Settings, event scrobble.Event, file state.File) bool {
+	if event.Path != "" {
+		return file.Path == event.Path
+	}
+
+	if file.Season != event.Season || file.Episode != event.Episode {
+		return false
+	}
+	if trakt.TitlesMatch(event.Show, file.Show) {
+		return true
+	}
+
+	showFolder := filepath.Base(filepath.Dir(filepath.Dir(file.Path)))
+	for _, item := range settings.Overrides {
+		if strings.EqualFold(showFolder, item.Folder) {
+			return item.Mapping.TraktName != "" && trakt.TitlesMatch(event.Show, item.Mapping.TraktName)
+		}
+	}
+	return false
+}
+
+// addScrobbledEpisodes merges the episodes that were reported as watched by media servers into
+// the watched shows from Trakt
+func addScrobbledEpisodes(user *trakt.User) error {
+	var scrobbles []state.Scrobble
+	err := withStateStore(func(store *state.Store) (err error) {
+		scrobbles, err = store.Scrobbles(time.Now().Add(-scrobbleRetention))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("could not load watched episodes reported by media servers: %w", err)
+	}
+
+	episodes := make([]trakt.WatchedEpisode, 0, len(scrobbles))
+	for _, watched := range scrobbles {
+		episodes = append(episodes, trakt.WatchedEpisode{
+			Show:      watched.Show,
+			Season:    watched.Season,
+			Episode:   watched.Episode,
+			WatchedAt: watched.Time,
+			WatchedBy: scrobbleWatchedBy(watched),
+		})
+	}
+	for _, watched := range user.AddWatchedEpisodes(episodes) {
+		scrobbleLog.Info("Ignored watched episode of a show that matches more than one watched show",
+			zap.String("source", watched.WatchedBy),
+			zap.String("show", watched.Show),
+			zap.Int("season", watched.Season),
+			zap.Int("episode", watched.Episode),
+		)
+	}
+	return nil
+}
+
+// scrobbleWatchedBy describes who reported a watched episode, such as "plex:alice", or only the
+// media server when the user was not reported
+func scrobbleWatchedBy(watched state.Scrobble) string {
+	if watched.User == "" {
+		return watched.Source
+	}
+	return watched.Source + ":" + watched.User
+}
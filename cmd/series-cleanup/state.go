@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/mediafile"
+	"github.com/bjw-s/series-cleanup/internal/state"
+)
+
+const stateDatabaseFile = "state.db"
+
+func stateDatabasePath() string {
+	return filepath.Join(configFolder, stateDatabaseFile)
+}
+
+// withStateStore opens the state database for the duration of fn.
+// The database is only kept open while it is used, so it can be shared by runs,
+// the web UI and other series-cleanup processes.
+func withStateStore(fn func(store *state.Store) error) error {
+	store, err := state.Open(stateDatabasePath())
+	if err != nil {
+		return fmt.Errorf("could not open state database: %w", err)
+	}
+	defer store.Close()
+	return fn(store)
+}
+
+// uiOverrides keeps the shows that are always skipped through the web UI as overrides in the
+// configuration folder, and reloads the configuration so the next run skips them
+type uiOverrides struct{}
+
+func (uiOverrides) SkipShow(folder string) error {
+	if err := config.SkipShow(configFolder, folder); err != nil {
+		return err
+	}
+	reloadConfig()
+	return nil
+}
+
+func (uiOverrides) UnskipShow(folder string) error {
+	if err := config.UnskipShow(configFolder, folder); err != nil {
+		return err
+	}
+	reloadConfig()
+	return nil
+}
+
+func (uiOverrides) SkippedShows() ([]string, error) {
+	return config.SkippedShows(configFolder)
+}
+
+// seeTvShowFiles records the TV show files found in scanFolder and returns when each was first seen
+func seeTvShowFiles(store *state.Store, scanFolder string, files []*mediafile.TVShowFile) (map[string]time.Time, error) {
+	seenFiles := make([]state.SeenFile, 0, len(files))
+	for _, file := range files {
+		seenFiles = append(seenFiles, state.SeenFile{
+			Path:       filepath.Join(file.Dir, file.Filename),
+			ScanFolder: scanFolder,
+			Show:       file.Show,
+			Season:     file.Season,
+			Episode:    file.Episode,
+			Size:       file.Size(),
+			ModTime:    file.ModTime(),
+		})
+	}
+	return store.SeeFiles(seenFiles, time.Now())
+}
+
+// recordDecisions stores what was done with every file during the run
+func recordDecisions(store *state.Store, result *runResult) error {
+	files := result.Files()
+	decisions := make([]state.Decision, 0, len(files))
+	for _, file := range files {
+		decisions = append(decisions, state.Decision{
+			Path:       file.Path,
+			ScanFolder: file.ScanFolder,
+			Show:       file.Show,
+			Season:     file.Season,
+			Episode:    file.Episode,
+			Size:       file.Size,
+			Action:     string(file.Action),
+			Reason:     file.Reason,
+			Time:       file.DecidedAt,
+			Deleted:    file.Action == actionDeleted,
+		})
+	}
+
+	return store.RecordDecisions(decisions)
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/filesystem"
+	"github.com/bjw-s/series-cleanup/internal/report"
+)
+
+const maxRecentErrors = 25
+
+// runStatus describes a single run for the status endpoint
+type runStatus struct {
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt time.Time  `json:"finishedAt"`
+	Summary    runSummary `json:"summary"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// statusError describes a recent error for the status endpoint
+type statusError struct {
+	Time  time.Time `json:"time"`
+	Path  string    `json:"path,omitempty"`
+	Error string    `json:"error"`
+}
+
+// daemonStatus keeps track of the state of the daemon for the health, readiness and status endpoints.
+// It is safe for concurrent use.
+type daemonStatus struct {
+	mutex                sync.Mutex
+	lastRun              *runStatus
+	nextRun              time.Time
+	traktTokenExpiresAt  time.Time
+	watchedShowsSyncedAt time.Time
+	watchedShowsSyncOK   bool
+	recentErrors         []statusError
+	lastReport           *report.Report
+	// remoteScanFolders tells for each remote scan folder if the last run that tried to open it succeeded
+	remoteScanFolders map[string]bool
+}
+
+// RunFinished registers the outcome of a run
+func (status *daemonStatus) RunFinished(startedAt time.Time, result *runResult, err error) {
+	status.mutex.Lock()
+	defer status.mutex.Unlock()
+
+	now := time.Now()
+	status.lastRun = &runStatus{
+		StartedAt:  startedAt,
+		FinishedAt: now,
+	}
+	if err != nil {
+		status.lastRun.Error = err.Error()
+	}
+
+	status.lastRun.Summary = result.Summary()
+	lastReport := result.Report()
+	status.lastReport = &lastReport
+
+	if expiresAt := result.TraktTokenExpiry(); !expiresAt.IsZero() {
+		status.traktTokenExpiresAt = expiresAt
+	}
+	syncedAt := result.WatchedShowsSyncedAt()
+	status.watchedShowsSyncOK = !syncedAt.IsZero()
+	if status.watchedShowsSyncOK {
+		status.watchedShowsSyncedAt = syncedAt
+	}
+
+	if status.remoteScanFolders == nil {
+		status.remoteScanFolders = map[string]bool{}
+	}
+	for scanFolder, reachable := range result.ScanFoldersReachable() {
+		if filesystem.IsRemote(scanFolder) {
+			status.remoteScanFolders[scanFolder] = reachable
+		}
+	}
+
+	var errs []statusError
+	for _, fileErr := range result.Errors() {
+		errs = append(errs, statusError{Time: now, Path: fileErr.Path, Error: fileErr.Err.Error()})
+	}
+	if err != nil && len(errs) == 0 {
+		errs = append(errs, statusError{Time: now, Error: err.Error()})
+	}
+	status.addErrors(errs...)
+}
+
+// SetNextRun registers when the next run is scheduled
+func (status *daemonStatus) SetNextRun(nextRun time.Time) {
+	status.mutex.Lock()
+	defer status.mutex.Unlock()
+	status.nextRun = nextRun
+}
+
+func (status *daemonStatus) addErrors(errs ...statusError) {
+	status.recentErrors = append(status.recentErrors, errs...)
+	if len(status.recentErrors) > maxRecentErrors {
+		status.recentErrors = status.recentErrors[len(status.recentErrors)-maxRecentErrors:]
+	}
+}
+
+// HandleHealthz reports that the process is alive
+func (status *daemonStatus) HandleHealthz(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// HandleReadyz reports if the Trakt token is valid, the last watched show sync succeeded, all local
+// scan folders are mounted and the last run could reach all remote scan folders
+func (status *daemonStatus) HandleReadyz(w http.ResponseWriter, _ *http.Request) {
+	status.mutex.Lock()
+	checks := map[string]string{}
+	switch {
+	case status.traktTokenExpiresAt.IsZero():
+		checks["traktToken"] = "not authenticated yet"
+	case time.Now().After(status.traktTokenExpiresAt):
+		checks["traktToken"] = "expired"
+	default:
+		checks["traktToken"] = "ok"
+	}
+	if status.watchedShowsSyncOK {
+		checks["watchedShowsSync"] = "ok"
+	} else {
+		checks["watchedShowsSync"] = "last sync failed or has not run yet"
+	}
+	remoteScanFolders := make(map[string]bool, len(status.remoteScanFolders))
+	for scanFolder, reachable := range status.remoteScanFolders {
+		remoteScanFolders[scanFolder] = reachable
+	}
+	status.mutex.Unlock()
+
+	// Connecting to remote scan folders is too slow for a probe, so they are reported as the
+	// last run found them
+	for _, scanFolder := range config.Current().ScanFolders {
+		if filesystem.IsRemote(scanFolder) {
+			reachable, tried := remoteScanFolders[scanFolder]
+			switch {
+			case !tried:
+				checks["scanFolder:"+scanFolder] = "not reached yet"
+			case reachable:
+				checks["scanFolder:"+scanFolder] = "ok"
+			default:
+				checks["scanFolder:"+scanFolder] = "not reachable"
+			}
+			continue
+		}
+
+		fileSystem, _, err := openScanFolder(config.Current(), scanFolder)
+		if err != nil {
+			checks["scanFolder:"+scanFolder] = "not mounted"
+			continue
+		}
+		fileSystem.Close()
+		checks["scanFolder:"+scanFolder] = "ok"
+	}
+
+	statusCode := http.StatusOK
+	for _, check := range checks {
+		if check != "ok" {
+			statusCode = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	writeJSON(w, statusCode, map[string]interface{}{
+		"ready":  statusCode == http.StatusOK,
+		"checks": checks,
+	})
+}
+
+// HandleStatus reports the last run summary, the next scheduled run and the most recent errors
+func (status *daemonStatus) HandleStatus(w http.ResponseWriter, _ *http.Request) {
+	status.mutex.Lock()
+	defer status.mutex.Unlock()
+
+	response := struct {
+		LastRun              *runStatus    `json:"lastRun"`
+		NextRun              *time.Time    `json:"nextRun,omitempty"`
+		TraktTokenExpiresAt  *time.Time    `json:"traktTokenExpiresAt,omitempty"`
+		WatchedShowsSyncedAt *time.Time    `json:"watchedShowsSyncedAt,omitempty"`
+		RecentErrors         []statusError `json:"recentErrors"`
+	}{
+		LastRun:              status.lastRun,
+		NextRun:              optionalTime(status.nextRun),
+		TraktTokenExpiresAt:  optionalTime(status.traktTokenExpiresAt),
+		WatchedShowsSyncedAt: optionalTime(status.watchedShowsSyncedAt),
+		RecentErrors:         append([]statusError{}, status.recentErrors...),
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// HandleReport renders the report of the last run, as HTML unless another format is requested
+// using the format query parameter
+func (status *daemonStatus) HandleReport(w http.ResponseWriter, r *http.Request) {
+	format := report.FormatHTML
+	if name := r.URL.Query().Get("format"); name != "" {
+		var err error
+		format, err = report.ParseFormat(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	status.mutex.Lock()
+	lastReport := status.lastReport
+	status.mutex.Unlock()
+
+	if lastReport == nil {
+		http.Error(w, "no run has finished yet", http.StatusNotFound)
+		return
+	}
+
+	content, err := lastReport.String(format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	_, _ = w.Write([]byte(content))
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(value)
+}
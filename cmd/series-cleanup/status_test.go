@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+)
+
+func TestReadyzReportsRemoteScanFoldersFromLastRun(t *testing.T) {
+	const remote = "sftp://media.example.com/tv"
+	previous := config.Current()
+	config.Set(&config.Settings{ScanFolders: []string{t.TempDir(), remote}})
+	t.Cleanup(func() { config.Set(previous) })
+
+	readyz := func(status *daemonStatus) map[string]string {
+		recorder := httptest.NewRecorder()
+		status.HandleReadyz(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var response struct {
+			Checks map[string]string `json:"checks"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatal(err)
+		}
+		return response.Checks
+	}
+
+	status := &daemonStatus{}
+	if check := readyz(status)["scanFolder:"+remote]; check != "not reached yet" {
+		t.Errorf("remote scan folder before the first run = %q, want not reached yet", check)
+	}
+
+	result := &runResult{}
+	result.SetScanFolderReachable(remote, false)
+	status.RunFinished(time.Now(), result, nil)
+	if check := readyz(status)["scanFolder:"+remote]; check != "not reachable" {
+		t.Errorf("remote scan folder after a failed run = %q, want not reachable", check)
+	}
+
+	// A run that stopped before the scan folder was opened keeps the previous outcome
+	status.RunFinished(time.Now(), &runResult{}, nil)
+	if check := readyz(status)["scanFolder:"+remote]; check != "not reachable" {
+		t.Errorf("remote scan folder after a run that did not open it = %q, want not reachable", check)
+	}
+
+	result = &runResult{}
+	result.SetScanFolderReachable(remote, true)
+	status.RunFinished(time.Now(), result, nil)
+	if check := readyz(status)["scanFolder:"+remote]; check != "ok" {
+		t.Errorf("remote scan folder after a successful run = %q, want ok", check)
+	}
+}
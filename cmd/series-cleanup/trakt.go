@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/bjw-s/series-cleanup/internal/metrics"
+	"github.com/bjw-s/series-cleanup/internal/trakt"
+	"go.uber.org/zap"
+)
+
+// traktLog logs the communication with Trakt
+var traktLog = logger.Named("trakt")
+
+func newTraktAPI(settings *config.Settings) trakt.API {
+	var traktAPI = trakt.API{}
+	traktAPI.ClientID = settings.Trakt.ClientID
+	traktAPI.ClientSecret = string(settings.Trakt.ClientSecret)
+	traktAPI.DataPath = settings.Trakt.CacheFolder
+	traktAPI.RequestObserver = metrics.TraktRequest
+	traktAPI.Logger = traktLog
+	return traktAPI
+}
+
+func authenticateWithTrakt(ctx context.Context, settings *config.Settings) (trakt.API, error) {
+	traktAPI := newTraktAPI(settings)
+	if err := traktAPI.Authenticate(ctx); err != nil {
+		return traktAPI, fmt.Errorf("could not authenticate with Trakt: %w", err)
+	}
+
+	metrics.TraktTokenExpiry(traktAPI.TokenExpiresAt)
+	traktLog.Info("Successfully authenticated with Trakt")
+	return traktAPI, nil
+}
+
+func getTraktUser(ctx context.Context, settings *config.Settings, traktAPI trakt.API) (*trakt.User, error) {
+	var traktUser = &trakt.User{}
+	traktUser.Name = settings.Trakt.User
+	traktUser.CacheMaxAge = time.Duration(int64(settings.Trakt.MaxCacheAgeHours) * int64(time.Hour))
+	traktUser.IgnoreCheckins = settings.Trakt.IgnoreCheckins
+
+	var err error
+	if settings.Trakt.WatchedSource == "history" {
+		err = traktUser.GetWatchedShowsFromHistory(ctx, traktAPI)
+	} else {
+		err = traktUser.GetWatchedShows(ctx, traktAPI)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not get watched shows from Trakt: %w", err)
+	}
+
+	if traktUser.Offline {
+		traktLog.Error("Trakt is unavailable, using cached watched shows",
+			zap.String("user", traktUser.Name),
+		)
+	} else if traktUser.FromCache {
+		traktLog.Info("Using cached watched shows",
+			zap.String("user", traktUser.Name),
+		)
+	}
+
+	return traktUser, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/bjw-s/series-cleanup/internal/filesystem"
+	"github.com/bjw-s/series-cleanup/internal/logger"
+	"github.com/bjw-s/series-cleanup/internal/mediafile"
+	"github.com/bjw-s/series-cleanup/internal/watcher"
+	"go.uber.org/zap"
+)
+
+// watchLog logs the changes to the scan folders in watch mode
+var watchLog = logger.Named("watch")
+
+// queuedFile is a file in the episode queue
+type queuedFile struct {
+	ScanFolder string
+	QueuedAt   time.Time
+}
+
+// episodeQueue contains the episodes in the scan folders that have not been deleted yet.
+// In watch mode, runs only evaluate the queued episodes instead of rescanning the scan folders.
+// It is safe for concurrent use.
+type episodeQueue struct {
+	mutex sync.Mutex
+	files map[string]queuedFile
+
+	// lastFullScan is when the last completed full scan started, it is zero until the first one completed
+	lastFullScan time.Time
+	// fullScanRequired is set when file system events were lost
+	fullScanRequired bool
+}
+
+func newEpisodeQueue() *episodeQueue {
+	return &episodeQueue{files: map[string]queuedFile{}}
+}
+
+// Scope returns the paths of the queued files by scan folder, or nil when the scan folders have
+// to be scanned completely according to settings
+func (queue *episodeQueue) Scope(settings *config.Settings, now time.Time) map[string][]string {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	fullScanInterval := time.Duration(settings.Daemon.FullScanIntervalHours) * time.Hour
+	if queue.lastFullScan.IsZero() || queue.fullScanRequired ||
+		(fullScanInterval > 0 && now.Sub(queue.lastFullScan) >= fullScanInterval) {
+		return nil
+	}
+
+	scope := map[string][]string{}
+	for path, file := range queue.files {
+		scope[file.ScanFolder] = append(scope[file.ScanFolder], path)
+	}
+	for _, paths := range scope {
+		sort.Strings(paths)
+	}
+	return scope
+}
+
+// RunFinished updates the queue with the outcome of a run with settings that started at startedAt.
+// After a completed full scan, the queue is replaced by all files that were not deleted,
+// otherwise the deleted files are removed from the queue.
+func (queue *episodeQueue) RunFinished(settings *config.Settings, fullScan bool, startedAt time.Time, result *runResult, err error) {
+	completed := err == nil || errors.Is(err, errPartialFailure)
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if fullScan && completed {
+		files := map[string]queuedFile{}
+		// Files that arrived while scanning might not have been seen by the scan
+		for path, file := range queue.files {
+			if !file.QueuedAt.Before(startedAt) {
+				files[path] = file
+			}
+		}
+		for _, record := range result.Files() {
+			if record.Action != actionDeleted {
+				files[record.Path] = queuedFile{ScanFolder: record.ScanFolder, QueuedAt: startedAt}
+			}
+		}
+		// Files that could not be processed are tried again in the next run
+		for _, fileErr := range result.Errors() {
+			if scanFolder := scanFolderOf(settings, fileErr.Path); !fileErr.Unrecognized && scanFolder != "" && mediafile.IsMediaFile(fileErr.Path) {
+				files[fileErr.Path] = queuedFile{ScanFolder: scanFolder, QueuedAt: startedAt}
+			}
+		}
+
+		queue.files = files
+		queue.lastFullScan = startedAt
+		queue.fullScanRequired = false
+		return
+	}
+
+	for _, record := range result.Files() {
+		if record.Action == actionDeleted {
+			delete(queue.files, record.Path)
+		}
+	}
+}
+
+// Add queues the file at path, it is ignored when it is not in one of the scan folders of settings
+func (queue *episodeQueue) Add(settings *config.Settings, path string) {
+	scanFolder := scanFolderOf(settings, path)
+	if scanFolder == "" {
+		return
+	}
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.files[path] = queuedFile{ScanFolder: scanFolder, QueuedAt: time.Now()}
+}
+
+// Remove removes the file at path from the queue, or all files in it when path is a folder
+func (queue *episodeQueue) Remove(path string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	prefix := path + string(filepath.Separator)
+	for queuedPath := range queue.files {
+		if queuedPath == path || strings.HasPrefix(queuedPath, prefix) {
+			delete(queue.files, queuedPath)
+		}
+	}
+}
+
+// RequireFullScan makes the next run scan the scan folders completely
+func (queue *episodeQueue) RequireFullScan() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.fullScanRequired = true
+}
+
+// Len returns the number of queued files
+func (queue *episodeQueue) Len() int {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return len(queue.files)
+}
+
+// watchScanFolders keeps the queue up to date with the files that are added to and removed from
+// the scan folders until ctx is cancelled
+func watchScanFolders(ctx context.Context, queue *episodeQueue) error {
+	fileWatcher, err := watcher.New(config.Current().ScanFolders, isWatchedFile)
+	if err != nil {
+		return fmt.Errorf("could not watch scan folders: %w", err)
+	}
+	go fileWatcher.Run(ctx)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-fileWatcher.Events:
+				switch event.Type {
+				case watcher.Added:
+					watchLog.Debug("Queued new file",
+						zap.String("file", event.Path),
+					)
+					queue.Add(config.Current(), event.Path)
+				case watcher.Removed:
+					queue.Remove(event.Path)
+				}
+			case err := <-fileWatcher.Errors:
+				watchLog.Error("Error while watching scan folders, the next run will scan them completely",
+					zap.Error(err),
+				)
+				queue.RequireFullScan()
+			}
+		}
+	}()
+	return nil
+}
+
+// isWatchedFile indicates if changes to the file at path are relevant for the episode queue
+func isWatchedFile(path string) bool {
+	return !strings.HasPrefix(filepath.Base(path), ".") && mediafile.IsMediaFile(path)
+}
+
+// scanFolderOf returns the scan folder of settings that contains path, or an empty string when
+// there is none. The path of a file in a remote scan folder is its path on the remote server.
+func scanFolderOf(settings *config.Settings, path string) string {
+	for _, scanFolder := range settings.ScanFolders {
+		rel, err := filepath.Rel(filesystem.Root(scanFolder), path)
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return scanFolder
+		}
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func newListWatchedCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list-watched",
+		Short: "List the shows that have been watched on Trakt",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			settings := config.Current()
+			traktAPI, err := authenticateWithTrakt(cmd.Context(), settings)
+			if err != nil {
+				return err
+			}
+
+			traktUser, err := getTraktUser(cmd.Context(), settings, traktAPI)
+			if err != nil {
+				return err
+			}
+
+			watchedShows := traktUser.WatchedShows
+			sort.SliceStable(watchedShows, func(i, j int) bool {
+				return strings.ToLower(watchedShows[i].Show.Title) < strings.ToLower(watchedShows[j].Show.Title)
+			})
+
+			writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "TITLE\tTRAKT\tSLUG\tTVDB\tIMDB\tTMDB\tEPISODES\tLAST WATCHED")
+			for _, watchedShow := range watchedShows {
+				episodes := 0
+				var lastWatched time.Time
+				for _, season := range watchedShow.Seasons {
+					episodes += len(season.Episodes)
+					for _, episode := range season.Episodes {
+						if episode.LastWatched.After(lastWatched) {
+							lastWatched = episode.LastWatched
+						}
+					}
+				}
+
+				ids := watchedShow.Show.IDS
+				fmt.Fprintf(writer, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+					watchedShow.Show.Title, ids.Trakt, ids.Slug, ids.TVDB, ids.IMDB, ids.TMDB,
+					episodes, lastWatched.Local().Format(time.RFC3339),
+				)
+			}
+			return writer.Flush()
+		},
+	}
+}
@@ -0,0 +1,133 @@
+// Package audit implements the append-only log of the files removed by series-cleanup
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+	"github.com/bjw-s/series-cleanup/internal/rotatingfile"
+)
+
+// maxLineSize is the longest audit entry that can be read back
+const maxLineSize = 1024 * 1024
+
+// ShowIDs are the ids of the Trakt show a removed file belonged to
+type ShowIDs struct {
+	Trakt int    `json:"trakt,omitempty"`
+	Slug  string `json:"slug,omitempty"`
+	TVDB  int    `json:"tvdb,omitempty"`
+	IMDB  string `json:"imdb,omitempty"`
+	TMDB  int    `json:"tmdb,omitempty"`
+}
+
+// Policy describes the configured rule that caused a file to be removed
+type Policy struct {
+	DeleteBasedOn              string `json:"deleteBasedOn"`
+	DeleteAfterHours           int    `json:"deleteAfterHours"`
+	DeleteAfterDownloadedHours int    `json:"deleteAfterDownloadedHours,omitempty"`
+	MinPlays                   int    `json:"minPlays,omitempty"`
+}
+
+// Entry records the removal of a single TV show file
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Path         string    `json:"path"`
+	Sidecars     []string  `json:"sidecars"`
+	Size         int64     `json:"size"`
+	Show         string    `json:"show"`
+	ShowIDs      ShowIDs   `json:"showIds"`
+	Season       int       `json:"season"`
+	Episode      int       `json:"episode"`
+	FirstWatched time.Time `json:"firstWatched"`
+	LastWatched  time.Time `json:"lastWatched"`
+	Plays        int       `json:"plays"`
+	Policy       Policy    `json:"policy"`
+	Users        []string  `json:"users"`
+}
+
+// Log is an append-only JSON lines file of audit entries.
+// It is safe for concurrent use.
+type Log struct {
+	file *rotatingfile.File
+}
+
+// Open opens the audit log at path, rotating it when it grows beyond maxSize bytes
+// and keeping at most maxBackups rotated files
+func Open(path string, maxSize int64, maxBackups int) (*Log, error) {
+	file, err := rotatingfile.Open(path, maxSize, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{file: file}, nil
+}
+
+// Record appends entry to the audit log and flushes it to disk
+func (log *Log) Record(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if _, err := log.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return log.file.Sync()
+}
+
+// Close closes the audit log
+func (log *Log) Close() error {
+	return log.file.Close()
+}
+
+// Read returns the entries in the audit log at path, including its rotated files,
+// that were recorded at or after since, oldest first
+func Read(path string, since time.Time) ([]Entry, error) {
+	paths := []string{path}
+	for n := 1; helpers.FileExists(rotatingfile.BackupPath(path, n)); n++ {
+		paths = append([]string{rotatingfile.BackupPath(path, n)}, paths...)
+	}
+
+	var entries []Entry
+	for _, path := range paths {
+		fileEntries, err := readFile(path, since)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+	return entries, nil
+}
+
+func readFile(path string, since time.Time) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%v:%d: %w", path, line, err)
+		}
+		if entry.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
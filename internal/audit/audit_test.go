@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadAcrossRotatedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Every entry is larger than the maximum size, so each ends up in its own file
+	log, err := Open(path, 100, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		err := log.Record(Entry{
+			Time:     start.Add(time.Duration(i) * time.Hour),
+			Path:     fmt.Sprintf("/media/Show/Season 1/S01E%02d.mkv", i+1),
+			Sidecars: []string{},
+			Episode:  i + 1,
+			Users:    []string{"me"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	log.Close()
+
+	entries, err := Read(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Episode != i+1 {
+			t.Errorf("entries[%d].Episode = %d, want %d", i, entry.Episode, i+1)
+		}
+	}
+
+	entries, err = Read(path, start.Add(90*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Episode != 3 {
+		t.Errorf("entries since = %+v, want only episode 3", entries)
+	}
+}
+
+func TestReadMissingLog(t *testing.T) {
+	entries, err := Read(filepath.Join(t.TempDir(), "audit.log"), time.Time{})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Read() = %v, %v, want no entries", entries, err)
+	}
+}
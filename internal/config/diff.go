@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// Notification settings often contain credentials without being a sensitiveString: headers such
+// as Authorization, and URLs of webhooks that contain a token in their path or query
+var (
+	notificationHeaderKey = regexp.MustCompile(`^notifications\[\d+\]\.headers\.`)
+	notificationURLKey    = regexp.MustCompile(`^notifications\[\d+\]\.url$`)
+)
+
+// Change is a configuration value that differs between two configurations.
+// Secrets are redacted, and a value that was added or removed is nil on the other side.
+type Change struct {
+	Key string      `json:"key"`
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Diff returns the values that differ between two configurations, sorted by key.
+// Keys use the names of the settings file, with the index of list items in brackets.
+func Diff(old, new *Settings) []Change {
+	oldValues := map[string]interface{}{}
+	flatten("", reflect.ValueOf(*old), oldValues)
+	newValues := map[string]interface{}{}
+	flatten("", reflect.ValueOf(*new), newValues)
+
+	keys := map[string]bool{}
+	for key := range oldValues {
+		keys[key] = true
+	}
+	for key := range newValues {
+		keys[key] = true
+	}
+
+	var changes []Change
+	for key := range keys {
+		oldValue, newValue := oldValues[key], newValues[key]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, Change{Key: key, Old: redact(key, oldValue), New: redact(key, newValue)})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// flatten adds all values in value to values, keyed by their path below prefix
+func flatten(prefix string, value reflect.Value, values map[string]interface{}) {
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			flatten(joinKey(prefix, fieldName(field)), value.Field(i), values)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), value.Index(i), values)
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			flatten(joinKey(prefix, fmt.Sprint(key.Interface())), value.MapIndex(key), values)
+		}
+	default:
+		values[prefix] = value.Interface()
+	}
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func redact(key string, value interface{}) interface{} {
+	if secret, ok := value.(sensitiveString); ok && secret != "" {
+		return secret.String()
+	}
+	text, ok := value.(string)
+	switch {
+	case !ok || text == "":
+		return value
+	case notificationHeaderKey.MatchString(key):
+		return sensitiveString(text).String()
+	case notificationURLKey.MatchString(key):
+		return redactURL(text)
+	default:
+		return value
+	}
+}
+
+// redactURL returns the scheme and host of a URL, so a change of server is still visible,
+// with anything else redacted. Other values are redacted completely.
+func redactURL(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return sensitiveString(value).String()
+	}
+	redacted := parsed.Scheme + "://" + parsed.Host
+	if parsed.User != nil || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" || parsed.Fragment != "" {
+		redacted += "/" + sensitiveString(value).String()
+	}
+	return redacted
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Settings{
+		DeleteAfterHours: 24,
+		ScanFolders:      []string{"/media/tv", "/media/anime"},
+		Trakt:            traktConfig{ClientSecret: "old-secret"},
+		Notifications: []notificationConfig{
+			{Type: "ntfy", Headers: map[string]string{"X-Test": "1"}},
+			{Type: "discord", URL: "https://discord.com/api/webhooks/1/old-token"},
+			{Type: "webhook", URL: "https://old.example.com"},
+		},
+	}
+	updated := &Settings{
+		DeleteAfterHours: 48,
+		ScanFolders:      []string{"/media/tv"},
+		Trakt:            traktConfig{ClientSecret: "new-secret"},
+		Notifications: []notificationConfig{
+			{Type: "ntfy", Headers: map[string]string{"X-Test": "2"}},
+			{Type: "discord", URL: "https://discord.com/api/webhooks/1/new-token"},
+			{Type: "webhook", URL: "https://new.example.com"},
+		},
+	}
+
+	want := []Change{
+		{Key: "deleteAfterHours", Old: 24, New: 48},
+		{Key: "notifications[0].headers.X-Test", Old: "[REDACTED]", New: "[REDACTED]"},
+		{Key: "notifications[1].url", Old: "https://discord.com/[REDACTED]", New: "https://discord.com/[REDACTED]"},
+		{Key: "notifications[2].url", Old: "https://old.example.com", New: "https://new.example.com"},
+		{Key: "scanFolders[1]", Old: "/media/anime", New: nil},
+		{Key: "trakt.clientSecret", Old: "[REDACTED]", New: "[REDACTED]"},
+	}
+	if got := Diff(old, updated); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+
+	if got := Diff(old, old); len(got) != 0 {
+		t.Errorf("Diff() of the same configuration = %+v, want no changes", got)
+	}
+}
+
+func TestMarshalRedactsNotificationCredentials(t *testing.T) {
+	settings := &Settings{
+		Notifications: []notificationConfig{
+			{Type: "webhook", URL: "https://example.com/hooks/secret-path?key=secret-query", Headers: map[string]string{"Authorization": "Bearer secret-header"}},
+			{Type: "ntfy", URL: "https://ntfy.sh"},
+		},
+	}
+
+	content, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "secret-") {
+		t.Errorf("json.Marshal() = %s, want the credentials to be redacted", content)
+	}
+	for _, want := range []string{`"url":"https://example.com/[REDACTED]"`, `"Authorization":"[REDACTED]"`, `"url":"https://ntfy.sh"`} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("json.Marshal() = %s, want it to contain %s", content, want)
+		}
+	}
+	if settings.Notifications[0].Headers["Authorization"] != "Bearer secret-header" {
+		t.Error("json.Marshal() changed the headers of the settings")
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envPrefix is the prefix of the environment variables that set configuration values
+const envPrefix = "SC_"
+
+// envFileSuffix marks an environment variable that contains the path of a file with the value,
+// which is meant for secrets that are mounted as files
+const envFileSuffix = "_FILE"
+
+// applyEnv sets the values of the environment variables in environ on the settings read from
+// the settings file. Variables that do not match a configuration key are reported.
+//
+// The name of a variable is the path of the key in upper case, with _ between the parts of the
+// path. Underscores between the words of a key are optional, so SC_DELETEAFTERHOURS and
+// SC_DELETE_AFTER_HOURS both set deleteAfterHours. Items of lists are set by their index, as in
+// SC_OVERRIDES_0_FOLDER, and a list of values can be set at once by separating them with spaces.
+func applyEnv(settings map[string]interface{}, environ []string) (map[string]interface{}, ValidationErrors) {
+	var errs ValidationErrors
+
+	sort.Strings(environ)
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		if !strings.HasPrefix(name, envPrefix) {
+			continue
+		}
+
+		segments := strings.Split(strings.TrimPrefix(name, envPrefix), "_")
+		if strings.HasSuffix(name, envFileSuffix) {
+			segments = segments[:len(segments)-1]
+			content, err := os.ReadFile(value)
+			if err != nil {
+				errs.add(name, "could not read file: %v", err)
+				continue
+			}
+			value = strings.TrimRight(string(content), "\r\n")
+		}
+
+		path, t, err := envPath(reflect.TypeOf(Settings{}), segments)
+		if err != nil {
+			errs.add(name, "%v", err)
+			continue
+		}
+
+		var converted interface{} = value
+		if t.Kind() == reflect.Slice {
+			var items []interface{}
+			for _, item := range strings.Fields(value) {
+				items = append(items, item)
+			}
+			converted = items
+		}
+		settings = setPath(settings, path, converted).(map[string]interface{})
+	}
+	return settings, errs
+}
+
+// errNoSuchKey is returned by envPath when the name of an environment variable does not match a key
+var errNoSuchKey = errors.New("does not match a configuration key")
+
+// envPath resolves the parts of the name of an environment variable to the path of the key in a
+// value of type t. The path consists of the names of keys and the indexes of list items.
+// It also returns the type of the value at the path.
+func envPath(t reflect.Type, segments []string) ([]interface{}, reflect.Type, error) {
+	if len(segments) == 0 {
+		if t.Kind() == reflect.Struct || t.Kind() == reflect.Map || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct) {
+			return nil, nil, errors.New("does not set a single value")
+		}
+		return nil, t, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		// The name of a key can span multiple parts, when there are underscores between its words
+		for n := 1; n <= len(segments); n++ {
+			field, ok := fieldByName(t, strings.Join(segments[:n], ""))
+			if !ok {
+				continue
+			}
+			path, fieldType, err := envPath(field.Type, segments[n:])
+			if err == nil {
+				return append([]interface{}{fieldName(field)}, path...), fieldType, nil
+			}
+			if !errors.Is(err, errNoSuchKey) {
+				return nil, nil, err
+			}
+		}
+		return nil, nil, errNoSuchKey
+	case reflect.Slice:
+		index, err := strconv.Atoi(segments[0])
+		if err != nil || index < 0 {
+			return nil, nil, fmt.Errorf("%s is not a valid list index", segments[0])
+		}
+		path, itemType, err := envPath(t.Elem(), segments[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		return append([]interface{}{index}, path...), itemType, nil
+	case reflect.Map:
+		// Map keys are free-form, so the rest of the name is the key
+		return []interface{}{strings.Join(segments, "_")}, t.Elem(), nil
+	default:
+		return nil, nil, errNoSuchKey
+	}
+}
+
+// setPath sets the value at path in node, creating the maps and list items on the way,
+// and returns the updated node. Keys of existing maps are matched case-insensitively.
+func setPath(node interface{}, path []interface{}, value interface{}) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+
+	switch key := path[0].(type) {
+	case int:
+		items, _ := node.([]interface{})
+		for len(items) <= key {
+			items = append(items, nil)
+		}
+		items[key] = setPath(items[key], path[1:], value)
+		return items
+	default:
+		name := key.(string)
+		values, ok := node.(map[string]interface{})
+		if !ok {
+			values = map[string]interface{}{}
+		}
+		for existing := range values {
+			if strings.EqualFold(existing, name) {
+				name = existing
+				break
+			}
+		}
+		values[name] = setPath(values[name], path[1:], value)
+		return values
+	}
+}
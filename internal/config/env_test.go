@@ -0,0 +1,78 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadEnvironment(t *testing.T) {
+	folder := t.TempDir()
+	media := t.TempDir()
+	anime := t.TempDir()
+	writeSettings(t, folder, "settings.yaml", `overrides:
+  - folder: Show
+    skip: true
+  - folder: Other
+trakt:
+  clientId: id
+  user: me
+`)
+	secretFile := writeSettings(t, t.TempDir(), "secret", "from-file\n")
+
+	t.Setenv("SC_DELETEAFTERHOURS", "48")
+	t.Setenv("SC_DELETE_AFTER_DOWNLOADED_HOURS", "12")
+	t.Setenv("SC_SCANFOLDERS", media+" "+anime)
+	t.Setenv("SC_OVERRIDES_1_SKIPSEASONS", "1 2")
+	t.Setenv("SC_OVERRIDES_2_FOLDER", "New")
+	t.Setenv("SC_OVERRIDES_2_MAPPING_TRAKTID", "42")
+	t.Setenv("SC_NOTIFICATIONS_0_TYPE", "ntfy")
+	t.Setenv("SC_NOTIFICATIONS_0_URL", "https://ntfy.sh")
+	t.Setenv("SC_NOTIFICATIONS_0_TOPIC", "series cleanup")
+	t.Setenv("SC_NOTIFICATIONS_0_HEADERS_Title", "Cleanup")
+	t.Setenv("SC_TRAKT_CLIENTSECRET_FILE", secretFile)
+
+	settings, err := Read(folder, "")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if settings.DeleteAfterHours != 48 || settings.DeleteAfterDownloadedHours != 12 {
+		t.Errorf("Read() hours = %d, %d", settings.DeleteAfterHours, settings.DeleteAfterDownloadedHours)
+	}
+	if !reflect.DeepEqual(settings.ScanFolders, []string{media, anime}) {
+		t.Errorf("Read() scanFolders = %v", settings.ScanFolders)
+	}
+	if len(settings.Overrides) != 3 || !settings.Overrides[0].Skip || settings.Overrides[1].Folder != "Other" ||
+		!reflect.DeepEqual(settings.Overrides[1].SkipSeasons, []int{1, 2}) ||
+		settings.Overrides[2].Folder != "New" || settings.Overrides[2].Mapping.TraktID != 42 {
+		t.Errorf("Read() overrides = %+v", settings.Overrides)
+	}
+	if len(settings.Notifications) != 1 || settings.Notifications[0].Topic != "series cleanup" || settings.Notifications[0].Headers["Title"] != "Cleanup" {
+		t.Errorf("Read() notifications = %+v", settings.Notifications)
+	}
+	if settings.Trakt.ClientSecret != "from-file" {
+		t.Errorf("Read() clientSecret = %q, want from-file", settings.Trakt.ClientSecret)
+	}
+}
+
+func TestReadEnvironmentErrors(t *testing.T) {
+	folder := t.TempDir()
+	writeSettings(t, folder, "settings.json", `{"trakt": {"clientId": "id", "clientSecret": "secret", "user": "me"}}`)
+
+	t.Setenv("SC_DELETEAFTERHOUR", "48")
+	t.Setenv("SC_OVERRIDES_FOLDER", "Show")
+	t.Setenv("SC_TRAKT", "me")
+	t.Setenv("SC_TRAKT_CLIENTSECRET_FILE", filepath.Join(folder, "missing"))
+
+	_, err := Read(folder, "")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Read() error = %v, want ValidationErrors", err)
+	}
+	want := []string{"SC_DELETEAFTERHOUR", "SC_OVERRIDES_FOLDER", "SC_TRAKT", "SC_TRAKT_CLIENTSECRET_FILE"}
+	if got := validationPaths(errs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() problems = %v, want %v", err, want)
+	}
+}
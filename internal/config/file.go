@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/knadh/koanf"
+	koanf_json "github.com/knadh/koanf/parsers/json"
+	koanf_toml "github.com/knadh/koanf/parsers/toml"
+	koanf_yaml "github.com/knadh/koanf/parsers/yaml"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+// settingsFiles are the names of the settings files that are looked for in the configuration folder
+var settingsFiles = []string{"settings.json", "settings.yaml", "settings.yml", "settings.toml"}
+
+// SettingsPath returns settingsFile when it is not empty, or otherwise the settings file in
+// configFolder. Exactly one settings file must exist in configFolder.
+func SettingsPath(configFolder, settingsFile string) (string, error) {
+	if settingsFile != "" {
+		return settingsFile, nil
+	}
+
+	var found []string
+	for _, name := range settingsFiles {
+		if candidate := filepath.Join(configFolder, name); helpers.FileExists(candidate) {
+			found = append(found, candidate)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("could not find a settings file in %s, expected one of %s", configFolder, strings.Join(settingsFiles, ", "))
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("found multiple settings files, keep only one of them: %s", strings.Join(found, ", "))
+	}
+}
+
+// parserFor returns the parser for a settings file based on its extension
+func parserFor(settingsFile string) (koanf.Parser, error) {
+	switch strings.ToLower(filepath.Ext(settingsFile)) {
+	case ".json":
+		return koanf_json.Parser(), nil
+	case ".yaml", ".yml":
+		return koanf_yaml.Parser(), nil
+	case ".toml":
+		return koanf_toml.Parser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported settings file format: %s, use .json, .yaml, .yml or .toml", settingsFile)
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSettings(t *testing.T, folder, name, content string) string {
+	t.Helper()
+	path := filepath.Join(folder, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSettingsFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "settings.json",
+			content: `{"deleteAfterHours": 48, "scanFolders": ["/media/tv"], "trakt": {"clientId": "id", "clientSecret": "secret", "user": "me"}}`,
+		},
+		{
+			name: "settings.yaml",
+			content: `deleteAfterHours: 48
+scanFolders:
+  - /media/tv
+trakt:
+  clientId: id
+  clientSecret: secret
+  user: me
+`,
+		},
+		{
+			name: "settings.toml",
+			content: `deleteAfterHours = 48
+scanFolders = ["/media/tv"]
+
+[trakt]
+clientId = "id"
+clientSecret = "secret"
+user = "me"
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			folder := t.TempDir()
+			writeSettings(t, folder, test.name, strings.ReplaceAll(test.content, "/media/tv", t.TempDir()))
+
+			settings, err := Read(folder, "")
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if settings.DeleteAfterHours != 48 || len(settings.ScanFolders) != 1 || settings.Trakt.User != "me" || settings.Trakt.ClientSecret != "secret" {
+				t.Errorf("Read() = %+v", settings)
+			}
+			// Defaults still apply
+			if settings.Concurrency != 4 || settings.Trakt.CacheFolder != folder {
+				t.Errorf("Read() did not apply defaults: %+v", settings)
+			}
+		})
+	}
+}
+
+func TestReadExplicitSettingsFile(t *testing.T) {
+	folder := t.TempDir()
+	path := writeSettings(t, t.TempDir(), "custom.yml", "trakt: {clientId: id, clientSecret: secret, user: other}\n")
+
+	settings, err := Read(folder, path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if settings.Trakt.User != "other" {
+		t.Errorf("Read() user = %q, want other", settings.Trakt.User)
+	}
+}
+
+func TestSettingsPathErrors(t *testing.T) {
+	folder := t.TempDir()
+	if _, err := SettingsPath(folder, ""); err == nil || !strings.Contains(err.Error(), "could not find a settings file") {
+		t.Errorf("SettingsPath() error = %v, want missing settings file", err)
+	}
+
+	writeSettings(t, folder, "settings.json", "{}")
+	writeSettings(t, folder, "settings.yaml", "")
+	if _, err := SettingsPath(folder, ""); err == nil || !strings.Contains(err.Error(), "multiple settings files") {
+		t.Errorf("SettingsPath() error = %v, want multiple settings files", err)
+	}
+
+	if _, err := Read(folder, writeSettings(t, folder, "settings.ini", "")); err == nil {
+		t.Error("Read() of an unsupported format succeeded")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema := Schema()
+
+	trakt := schemaProperty(schema, []string{"trakt"})
+	if required, _ := trakt["required"].([]string); strings.Join(required, ",") != "clientId,clientSecret,user" {
+		t.Errorf("trakt required = %v", trakt["required"])
+	}
+
+	deleteBasedOn := schemaProperty(schema, []string{"deleteBasedOn"})
+	if deleteBasedOn["default"] != "lastWatched" || len(deleteBasedOn["enum"].([]interface{})) != 3 {
+		t.Errorf("deleteBasedOn = %v", deleteBasedOn)
+	}
+
+	threshold := schemaProperty(schema, []string{"matching", "similarityThreshold"})
+	if threshold["type"] != "number" || threshold["minimum"] != int64(0) || threshold["maximum"] != int64(1) {
+		t.Errorf("similarityThreshold = %v", threshold)
+	}
+
+	triggers := schemaProperty(schema, []string{"notifications"})["items"].(map[string]interface{})["properties"].(map[string]interface{})["triggers"].(map[string]interface{})
+	if items := triggers["items"].(map[string]interface{}); len(items["enum"].([]interface{})) != 3 {
+		t.Errorf("notification triggers = %v", triggers)
+	}
+}
@@ -3,24 +3,38 @@ package config
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"os"
 	"path"
-	"strings"
+	"reflect"
+	"sync/atomic"
 
 	"github.com/knadh/koanf"
-	koanf_json "github.com/knadh/koanf/parsers/json"
 	"github.com/knadh/koanf/providers/confmap"
-	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/file"
 
 	"github.com/bjw-s/series-cleanup/internal/helpers"
 	"github.com/bjw-s/series-cleanup/internal/mediafile"
-	"github.com/go-playground/validator/v10"
-	flag "github.com/spf13/pflag"
+	"github.com/oriser/regroup"
 )
 
-// Config exposes the collected configuration
-var Config config
+// current holds the active configuration, it is replaced as a whole when the configuration is reloaded
+var current atomic.Pointer[Settings]
+
+func init() {
+	current.Store(&Settings{})
+}
+
+// Current returns the active configuration. The returned settings must not be modified,
+// and are not affected by a later reload.
+func Current() *Settings {
+	return current.Load()
+}
+
+// Set makes settings the active configuration
+func Set(settings *Settings) {
+	current.Store(settings)
+}
 
 type sensitiveString string
 
@@ -31,78 +45,249 @@ func (s sensitiveString) MarshalJSON() ([]byte, error) {
 	return json.Marshal("[REDACTED]")
 }
 
+type approvalConfig struct {
+	Enabled bool            `mapstructure:"enabled" json:"enabled"`
+	Token   sensitiveString `mapstructure:"token" json:"token" validate:"required_if=Enabled true"`
+}
+
+type auditConfig struct {
+	Enabled    bool   `mapstructure:"enabled" json:"enabled"`
+	Path       string `mapstructure:"path" json:"path"`
+	MaxSizeMB  int    `mapstructure:"maxSizeMB" json:"maxSizeMB" validate:"gte=0"`
+	MaxBackups int    `mapstructure:"maxBackups" json:"maxBackups" validate:"gte=0"`
+}
+
+type daemonConfig struct {
+	IntervalMinutes       int  `mapstructure:"intervalMinutes" json:"intervalMinutes" validate:"gte=1"`
+	Watch                 bool `mapstructure:"watch" json:"watch"`
+	FullScanIntervalHours int  `mapstructure:"fullScanIntervalHours" json:"fullScanIntervalHours" validate:"gte=0"`
+}
+
+type logConfig struct {
+	Format     string            `mapstructure:"format" json:"format" validate:"oneof=json console"`
+	Levels     map[string]string `mapstructure:"levels" json:"levels" validate:"dive,oneof=debug info warn error"`
+	Path       string            `mapstructure:"path" json:"path"`
+	MaxSizeMB  int               `mapstructure:"maxSizeMB" json:"maxSizeMB" validate:"gte=0"`
+	MaxBackups int               `mapstructure:"maxBackups" json:"maxBackups" validate:"gte=0"`
+}
+
+type scrobbleConfig struct {
+	Enabled bool            `mapstructure:"enabled" json:"enabled"`
+	Token   sensitiveString `mapstructure:"token" json:"token" validate:"required_if=Enabled true"`
+}
+
+type serverConfig struct {
+	ListenAddress string `mapstructure:"listenAddress" json:"listenAddress"`
+}
+
 type folderOverride struct {
-	Folder      string                `mapstructure:"folder"`
-	Mapping     mediafile.ShowMapping `mapstructure:"mapping"`
-	Skip        bool                  `mapstructure:"skip"`
-	SkipSeasons []int                 `mapstructure:"skipSeasons"`
+	Folder      string                `mapstructure:"folder" json:"folder" validate:"required"`
+	Mapping     mediafile.ShowMapping `mapstructure:"mapping" json:"mapping"`
+	Skip        bool                  `mapstructure:"skip" json:"skip"`
+	SkipSeasons []int                 `mapstructure:"skipSeasons" json:"skipSeasons" validate:"dive,gte=0"`
+}
+
+type matchingConfig struct {
+	SimilarityThreshold float64 `mapstructure:"similarityThreshold" json:"similarityThreshold" validate:"gte=0,lte=1"`
+	TraktSearch         bool    `mapstructure:"traktSearch" json:"traktSearch"`
+}
+
+type smtpConfig struct {
+	Host     string          `mapstructure:"host" json:"host"`
+	Port     int             `mapstructure:"port" json:"port" validate:"gte=0,lte=65535"`
+	Username string          `mapstructure:"username" json:"username"`
+	Password sensitiveString `mapstructure:"password" json:"password"`
+	From     string          `mapstructure:"from" json:"from"`
+	To       []string        `mapstructure:"to" json:"to"`
+}
+
+type notificationConfig struct {
+	Type         string            `mapstructure:"type" json:"type" validate:"oneof=webhook ntfy gotify discord slack smtp"`
+	Triggers     []string          `mapstructure:"triggers" json:"triggers" validate:"dive,oneof=deletion error always"`
+	URL          string            `mapstructure:"url" json:"url" validate:"required_unless=Type smtp"`
+	Method       string            `mapstructure:"method" json:"method"`
+	Headers      map[string]string `mapstructure:"headers" json:"headers"`
+	Body         string            `mapstructure:"body" json:"body"`
+	Topic        string            `mapstructure:"topic" json:"topic" validate:"required_if=Type ntfy"`
+	Token        sensitiveString   `mapstructure:"token" json:"token" validate:"required_if=Type gotify"`
+	Priority     int               `mapstructure:"priority" json:"priority"`
+	SMTP         smtpConfig        `mapstructure:"smtp" json:"smtp"`
+	AttachReport bool              `mapstructure:"attachReport" json:"attachReport"`
+	ReportFormat string            `mapstructure:"reportFormat" json:"reportFormat" validate:"omitempty,oneof=text markdown html"`
+}
+
+// MarshalJSON redacts the header values and the path and query of the URL, as they often contain
+// credentials, so the configuration can be printed and logged
+func (notification notificationConfig) MarshalJSON() ([]byte, error) {
+	type plainNotificationConfig notificationConfig
+	redacted := plainNotificationConfig(notification)
+	if redacted.URL != "" {
+		redacted.URL = redactURL(redacted.URL)
+	}
+	if len(notification.Headers) > 0 {
+		redacted.Headers = make(map[string]string, len(notification.Headers))
+		for name, value := range notification.Headers {
+			redacted.Headers[name] = sensitiveString(value).String()
+		}
+	}
+	return json.Marshal(redacted)
+}
+
+type reportConfig struct {
+	Path   string `mapstructure:"path" json:"path"`
+	Format string `mapstructure:"format" json:"format" validate:"omitempty,oneof=text markdown html"`
 }
 
 type traktConfig struct {
-	CacheFolder  string          `mapstructure:"cacheFolder"`
-	ClientID     string          `mapstructure:"clientId" validate:"required"`
-	ClientSecret sensitiveString `mapstructure:"clientSecret" validate:"required"`
-	User         string          `mapstructure:"user" validate:"required"`
+	CacheFolder      string          `mapstructure:"cacheFolder" json:"cacheFolder"`
+	ClientID         string          `mapstructure:"clientId" json:"clientId" validate:"required"`
+	ClientSecret     sensitiveString `mapstructure:"clientSecret" json:"clientSecret" validate:"required"`
+	IgnoreCheckins   bool            `mapstructure:"ignoreCheckins" json:"ignoreCheckins"`
+	MaxCacheAgeHours int             `mapstructure:"maxCacheAgeHours" json:"maxCacheAgeHours" validate:"gte=0"`
+	User             string          `mapstructure:"user" json:"user" validate:"required"`
+	WatchedSource    string          `mapstructure:"watchedSource" json:"watchedSource" validate:"oneof=watched history"`
 }
 
-type config struct {
-	DeleteAfterHours int              `mapstructure:"deleteAfterHours"`
-	DryRun           bool             `mapstructure:"dryRun"`
-	FolderRegex      string           `mapstructure:"folderRegex"`
-	LogLevel         string           `mapstructure:"logLevel"`
-	Overrides        []folderOverride `mapstructure:"overrides"`
-	ScanFolders      []string         `mapstructure:"scanFolders"`
-	Trakt            traktConfig      `mapstructure:"trakt"`
+// Settings is the collected configuration
+type Settings struct {
+	Approval                   approvalConfig       `mapstructure:"approval" json:"approval"`
+	Audit                      auditConfig          `mapstructure:"audit" json:"audit"`
+	Concurrency                int                  `mapstructure:"concurrency" json:"concurrency" validate:"gte=1"`
+	Daemon                     daemonConfig         `mapstructure:"daemon" json:"daemon"`
+	DeleteAfterHours           int                  `mapstructure:"deleteAfterHours" json:"deleteAfterHours" validate:"gte=0"`
+	DeleteAfterDownloadedHours int                  `mapstructure:"deleteAfterDownloadedHours" json:"deleteAfterDownloadedHours" validate:"gte=0"`
+	DeleteBasedOn              string               `mapstructure:"deleteBasedOn" json:"deleteBasedOn" validate:"oneof=lastWatched firstWatched playCount"`
+	DryRun                     bool                 `mapstructure:"dryRun" json:"dryRun"`
+	FailFast                   bool                 `mapstructure:"failFast" json:"failFast"`
+	FolderRegex                string               `mapstructure:"folderRegex" json:"folderRegex"`
+	Log                        logConfig            `mapstructure:"log" json:"log"`
+	LogLevel                   string               `mapstructure:"logLevel" json:"logLevel" validate:"oneof=debug info warn error"`
+	Matching                   matchingConfig       `mapstructure:"matching" json:"matching"`
+	MinPlays                   int                  `mapstructure:"minPlays" json:"minPlays" validate:"gte=1"`
+	Notifications              []notificationConfig `mapstructure:"notifications" json:"notifications" validate:"dive"`
+	Overrides                  []folderOverride     `mapstructure:"overrides" json:"overrides"`
+	Remotes                    []remoteConfig       `mapstructure:"remotes" json:"remotes" validate:"dive"`
+	Report                     reportConfig         `mapstructure:"report" json:"report"`
+	ScanFolders                []string             `mapstructure:"scanFolders" json:"scanFolders"`
+	Scrobble                   scrobbleConfig       `mapstructure:"scrobble" json:"scrobble"`
+	Server                     serverConfig         `mapstructure:"server" json:"server"`
+	Trakt                      traktConfig          `mapstructure:"trakt" json:"trakt"`
+
+	// folderRegex is FolderRegex compiled when the configuration was validated
+	folderRegex *regroup.ReGroup
 }
 
-func init() {
-	var k = koanf.New(".")
+// CompiledFolderRegex returns the compiled folderRegex, or nil when it is empty
+func (settings *Settings) CompiledFolderRegex() *regroup.ReGroup {
+	return settings.folderRegex
+}
 
-	// Use the POSIX compliant pflag lib instead of Go's flag lib.
-	var configFolder = flag.String("configFolder", "/config", "path to store the configuration")
-	flag.Parse()
+// Load reads the configuration and makes it the active configuration.
+// See Read for the meaning of configFolder and settingsFile.
+func Load(configFolder, settingsFile string) error {
+	settings, err := Read(configFolder, settingsFile)
+	if err != nil {
+		return err
+	}
+	Set(settings)
+	return nil
+}
+
+// defaults returns the default values of the configuration as a flat map with the "." delimiter
+func defaults(configFolder string) map[string]interface{} {
+	return map[string]interface{}{
+		"audit.enabled":                true,
+		"audit.path":                   path.Join(configFolder, "audit.log"),
+		"audit.maxSizeMB":              10,
+		"audit.maxBackups":             5,
+		"concurrency":                  4,
+		"daemon.intervalMinutes":       60,
+		"daemon.fullScanIntervalHours": 24,
+		"dryRun":                       false,
+		"failFast":                     false,
+		"deleteAfterHours":             24,
+		"deleteBasedOn":                "lastWatched",
+		"minPlays":                     1,
+		"folderRegex":                  "(?P<Show>.*)",
+		"log.format":                   "json",
+		"log.maxSizeMB":                10,
+		"log.maxBackups":               5,
+		"logLevel":                     "info",
+		"trakt.cacheFolder":            configFolder,
+		"trakt.maxCacheAgeHours":       72,
+		"trakt.watchedSource":          "watched",
+	}
+}
+
+// Read reads the configuration from settingsFile, or from the settings file that is found in
+// configFolder when it is empty, merges it with the defaults and the SC_ environment variables and
+// validates the result. The overrides that were added through the web UI are read from
+// UIOverridesFile in configFolder. Problems with the configuration are returned as ValidationErrors.
+// configFolder is also where series-cleanup stores its data.
+func Read(configFolder, settingsFile string) (*Settings, error) {
+	var k = koanf.New(".")
 
 	// Check pre-requisites
-	if !helpers.FolderExists(*configFolder) {
-		log.Fatalf("Could not find configuration folder: %s", *configFolder)
+	if !helpers.FolderExists(configFolder) {
+		return nil, fmt.Errorf("could not find configuration folder: %s", configFolder)
+	}
+
+	settingsFile, err := SettingsPath(configFolder, settingsFile)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := parserFor(settingsFile)
+	if err != nil {
+		return nil, err
 	}
 
 	// Load default values using the confmap provider.
-	// We provide a flat map with the "." delimiter.
-	k.Load(confmap.Provider(map[string]interface{}{
-		"dryRun":            false,
-		"deleteAfterHours":  24,
-		"folderRegex":       "(?P<Show>.*)",
-		"loglevel":          "info",
-		"trakt.CacheFolder": configFolder,
-	}, "."), nil)
-
-	// Load provided JSON config
-	if err := k.Load(file.Provider(path.Join(*configFolder, "settings.json")), koanf_json.Parser()); err != nil {
-		log.Fatalf("Error loading file: %v", err)
-	}
-
-	// Load environment variables and merge into the loaded config.
-	k.Load(env.ProviderWithValue("SC_", ".", func(s string, v string) (string, interface{}) {
-		// Strip out the SC_ prefix and lowercase and get the key while also replacing
-		// the _ character with . in the key (koanf delimeter).
-		key := strings.Replace(strings.ToLower(strings.TrimPrefix(s, "SC_")), "_", ".", -1)
-
-		// If there is a space in the value, split the value into a slice by the space.
-		if strings.Contains(v, " ") {
-			return key, strings.Split(v, " ")
-		}
+	if err := k.Load(confmap.Provider(defaults(configFolder), "."), nil); err != nil {
+		return nil, fmt.Errorf("error loading defaults: %w", err)
+	}
 
-		// Otherwise, return the plain string.
-		return key, v
-	}), nil)
+	// Load provided settings file
+	fileSettings := koanf.New(".")
+	if err := fileSettings.Load(file.Provider(settingsFile), parser); err != nil {
+		return nil, fmt.Errorf("error loading file %s: %w", settingsFile, err)
+	}
+	settings := fileSettings.Raw()
+	var errs ValidationErrors
+	unknownKeys("", reflect.TypeOf(Settings{}), settings, &errs)
+	if err := errs.orNil(); err != nil {
+		return nil, err
+	}
 
-	k.Unmarshal("", &Config)
+	// Replace secret references by the secrets they refer to
+	resolveSecrets("", reflect.TypeOf(Settings{}), settings, &errs)
+	if err := errs.orNil(); err != nil {
+		return nil, err
+	}
 
-	// Validate the rendered configuration
-	validate := validator.New()
+	// Set the values of environment variables on top of the settings file
+	settings, errs = applyEnv(settings, os.Environ())
+	if err := errs.orNil(); err != nil {
+		return nil, err
+	}
+	if err := k.Load(confmap.Provider(settings, ""), nil); err != nil {
+		return nil, fmt.Errorf("error loading settings: %w", err)
+	}
+
+	var loaded Settings
+	if err := k.Unmarshal("", &loaded); err != nil {
+		return nil, fmt.Errorf("error parsing configuration: %w", err)
+	}
 
-	if err := validate.Struct(&Config); err != nil {
-		log.Fatalf("Configuration validation failed: %v\n", err)
+	// Add the overrides from the web UI
+	uiOverrides, err := readUIOverrides(configFolder)
+	if err != nil {
+		return nil, err
+	}
+	mergeUIOverrides(&loaded, uiOverrides)
+
+	// Validate the rendered configuration
+	if err := validate(&loaded); err != nil {
+		return nil, err
 	}
+	return &loaded, nil
 }
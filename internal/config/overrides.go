@@ -0,0 +1,108 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// UIOverridesFile is the file in the configuration folder in which the web UI keeps the
+// overrides it added, so the settings file itself is never rewritten
+const UIOverridesFile = "ui-overrides.json"
+
+// readUIOverrides returns the overrides that were added through the web UI
+func readUIOverrides(configFolder string) ([]folderOverride, error) {
+	content, err := os.ReadFile(filepath.Join(configFolder, UIOverridesFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides []folderOverride
+	if err := json.Unmarshal(content, &overrides); err != nil {
+		return nil, fmt.Errorf("error parsing file %s: %w", UIOverridesFile, err)
+	}
+	return overrides, nil
+}
+
+func writeUIOverrides(configFolder string, overrides []folderOverride) error {
+	content, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The file is replaced at once, so a reload never reads a partially written file
+	path := filepath.Join(configFolder, UIOverridesFile)
+	temporary := path + ".tmp"
+	if err := os.WriteFile(temporary, content, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, path)
+}
+
+// mergeUIOverrides adds the overrides from the web UI to the overrides of the settings file.
+// A show that is already overridden in the settings file is skipped by that override.
+func mergeUIOverrides(settings *Settings, overrides []folderOverride) {
+	for _, uiOverride := range overrides {
+		merged := false
+		for i := range settings.Overrides {
+			if strings.EqualFold(settings.Overrides[i].Folder, uiOverride.Folder) {
+				settings.Overrides[i].Skip = settings.Overrides[i].Skip || uiOverride.Skip
+				merged = true
+			}
+		}
+		if !merged {
+			settings.Overrides = append(settings.Overrides, uiOverride)
+		}
+	}
+}
+
+// SkipShow adds an override to the web UI overrides in configFolder that always skips the show in folder
+func SkipShow(configFolder, folder string) error {
+	overrides, err := readUIOverrides(configFolder)
+	if err != nil {
+		return err
+	}
+	for _, override := range overrides {
+		if strings.EqualFold(override.Folder, folder) {
+			return nil
+		}
+	}
+	return writeUIOverrides(configFolder, append(overrides, folderOverride{Folder: folder, Skip: true}))
+}
+
+// UnskipShow removes the override of the show in folder from the web UI overrides in configFolder.
+// Overrides in the settings file are not affected.
+func UnskipShow(configFolder, folder string) error {
+	overrides, err := readUIOverrides(configFolder)
+	if err != nil {
+		return err
+	}
+	kept := make([]folderOverride, 0, len(overrides))
+	for _, override := range overrides {
+		if !strings.EqualFold(override.Folder, folder) {
+			kept = append(kept, override)
+		}
+	}
+	return writeUIOverrides(configFolder, kept)
+}
+
+// SkippedShows returns the show folders that are skipped by the web UI overrides in configFolder
+func SkippedShows(configFolder string) ([]string, error) {
+	overrides, err := readUIOverrides(configFolder)
+	if err != nil {
+		return nil, err
+	}
+	folders := make([]string, 0, len(overrides))
+	for _, override := range overrides {
+		if override.Skip {
+			folders = append(folders, override.Folder)
+		}
+	}
+	return folders, nil
+}
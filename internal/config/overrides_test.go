@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSkipShowAddsOverride(t *testing.T) {
+	folder := t.TempDir()
+	writeSettings(t, folder, "settings.yaml", `overrides:
+  - folder: Show
+    skipSeasons: [1]
+trakt:
+  clientId: id
+  clientSecret: secret
+  user: me
+`)
+
+	for _, show := range []string{"show", "Other", "other"} {
+		if err := SkipShow(folder, show); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if skippedShows, err := SkippedShows(folder); err != nil || !reflect.DeepEqual(skippedShows, []string{"show", "Other"}) {
+		t.Errorf("SkippedShows() = %v, %v", skippedShows, err)
+	}
+
+	settings, err := Read(folder, "")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := []folderOverride{
+		{Folder: "Show", Skip: true, SkipSeasons: []int{1}},
+		{Folder: "Other", Skip: true},
+	}
+	if !reflect.DeepEqual(settings.Overrides, want) {
+		t.Errorf("Overrides = %+v, want %+v", settings.Overrides, want)
+	}
+
+	if err := UnskipShow(folder, "other"); err != nil {
+		t.Fatal(err)
+	}
+	settings, err = Read(folder, "")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(settings.Overrides) != 1 || !settings.Overrides[0].Skip {
+		t.Errorf("Overrides = %+v after UnskipShow, want only the override of the settings file", settings.Overrides)
+	}
+}
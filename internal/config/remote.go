@@ -0,0 +1,56 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/bjw-s/series-cleanup/internal/filesystem"
+)
+
+type remoteConfig struct {
+	URL                   string          `mapstructure:"url" json:"url" validate:"required"`
+	Username              string          `mapstructure:"username" json:"username"`
+	Password              sensitiveString `mapstructure:"password" json:"password"`
+	PrivateKeyFile        string          `mapstructure:"privateKeyFile" json:"privateKeyFile"`
+	KnownHostsFile        string          `mapstructure:"knownHostsFile" json:"knownHostsFile"`
+	InsecureIgnoreHostKey bool            `mapstructure:"insecureIgnoreHostKey" json:"insecureIgnoreHostKey"`
+}
+
+// RemoteCredentials returns the credentials used to connect to the remote scan folder at
+// location. They are taken from the remote with the same scheme and host whose path is the
+// longest prefix of the path of location.
+func (settings *Settings) RemoteCredentials(location string) filesystem.Credentials {
+	var credentials filesystem.Credentials
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return credentials
+	}
+
+	longest := -1
+	for _, remote := range settings.Remotes {
+		remoteURL, err := url.Parse(remote.URL)
+		if err != nil || remoteURL.Scheme != parsed.Scheme || remoteURL.Host != parsed.Host {
+			continue
+		}
+		prefix := strings.TrimSuffix(remoteURL.Path, "/")
+		if parsed.Path != prefix && !strings.HasPrefix(parsed.Path, prefix+"/") {
+			continue
+		}
+		if len(prefix) <= longest {
+			continue
+		}
+
+		longest = len(prefix)
+		credentials = filesystem.Credentials{
+			Username:              remote.Username,
+			Password:              string(remote.Password),
+			PrivateKeyFile:        remote.PrivateKeyFile,
+			KnownHostsFile:        remote.KnownHostsFile,
+			InsecureIgnoreHostKey: remote.InsecureIgnoreHostKey,
+		}
+		if credentials.Username == "" && remoteURL.User != nil {
+			credentials.Username = remoteURL.User.Username()
+		}
+	}
+	return credentials
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Schema returns a JSON Schema of the settings file, generated from the Settings struct and its
+// validation rules. Defaults are those of the default configuration folder /config.
+func Schema() map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(Settings{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "series-cleanup settings"
+	// Editors use the $schema key to find the schema of a settings file
+	schema["properties"].(map[string]interface{})["$schema"] = map[string]interface{}{"type": "string"}
+
+	for key, value := range defaults("/config") {
+		if property := schemaProperty(schema, strings.Split(key, ".")); property != nil {
+			property["default"] = value
+		}
+	}
+	return schema
+}
+
+// schemaFor returns the schema of values of type t
+func schemaFor(t reflect.Type) map[string]interface{} {
+	if t == sensitiveStringType {
+		return secretSchema()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name := fieldName(field)
+			property := schemaFor(field.Type)
+			if applyRules(property, field.Type, field.Tag.Get("validate")) {
+				required = append(required, name)
+			}
+			properties[name] = property
+		}
+
+		schema := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// applyRules adds the validation rules of a field of type t to its schema, and returns if the
+// field is required. Rules after dive apply to the items of a list or the values of a map.
+func applyRules(schema map[string]interface{}, t reflect.Type, rules string) bool {
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			items, ok := schema["items"].(map[string]interface{})
+			if !ok {
+				items, ok = schema["additionalProperties"].(map[string]interface{})
+			}
+			if ok {
+				_, after, _ := strings.Cut(rules, "dive")
+				applyRules(items, t.Elem(), strings.TrimPrefix(after, ","))
+			}
+			return required
+		case "oneof":
+			var enum []interface{}
+			for _, option := range strings.Fields(value) {
+				enum = append(enum, schemaValue(t, option))
+			}
+			schema["enum"] = enum
+		case "gte", "lte":
+			schema[boundKeyword(t, name)] = schemaValue(reflect.TypeOf(0), value)
+		}
+	}
+	return required
+}
+
+// boundKeyword returns the JSON Schema keyword of a gte or lte rule, which limit the length of
+// strings and lists and the value of numbers
+func boundKeyword(t reflect.Type, rule string) string {
+	prefix := map[string]string{"gte": "min", "lte": "max"}[rule]
+	switch t.Kind() {
+	case reflect.String:
+		return prefix + "Length"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return prefix + "Items"
+	default:
+		return prefix + "imum"
+	}
+}
+
+// schemaValue converts a value from a validation rule to the type of the field
+func schemaValue(t reflect.Type, value string) interface{} {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return number
+		}
+	case reflect.Float32, reflect.Float64:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	}
+	return value
+}
+
+// schemaProperty returns the schema of the property at path, or nil if there is none
+func schemaProperty(schema map[string]interface{}, path []string) map[string]interface{} {
+	for _, name := range path {
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		schema, ok = properties[name].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+	}
+	return schema
+}
+
+// fieldName returns the name of a field in the settings file
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"mapstructure", "json"} {
+		if name := strings.Split(field.Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// secretSchema returns the schema of a sensitive value, which is a string or a secret reference
+func secretSchema() map[string]interface{} {
+	reference := func(source string, schema map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           map[string]interface{}{source: schema},
+			"required":             []string{source},
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			reference("file", map[string]interface{}{"type": "string"}),
+			reference("env", map[string]interface{}{"type": "string"}),
+			reference("exec", map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "minItems": 1}),
+		},
+	}
+}
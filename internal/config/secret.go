@@ -0,0 +1,137 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// secretExecTimeout is how long a command that outputs a secret may run
+const secretExecTimeout = 30 * time.Second
+
+var sensitiveStringType = reflect.TypeOf(sensitiveString(""))
+
+// secretSources are the keys of a secret reference, one of which must be set
+var secretSources = []string{"file", "env", "exec"}
+
+// resolveSecrets replaces the secret references in value, as read from the settings file, by the
+// secrets they refer to, and returns the updated value. A secret reference is an object instead of
+// a string as the value of a sensitive key, such as {"file": "/run/secrets/trakt"}.
+func resolveSecrets(path string, t reflect.Type, value interface{}, errs *ValidationErrors) interface{} {
+	if t == sensitiveStringType {
+		reference, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		secret, err := resolveSecret(reference)
+		if err != nil {
+			errs.add(path, "%v", err)
+			return ""
+		}
+		return secret
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		for key, item := range values {
+			if field, ok := fieldByName(t, key); ok {
+				values[key] = resolveSecrets(joinKey(path, key), field.Type, item, errs)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		for i, item := range items {
+			items[i] = resolveSecrets(fmt.Sprintf("%s[%d]", path, i), t.Elem(), item, errs)
+		}
+	case reflect.Map:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		for key, item := range values {
+			values[key] = resolveSecrets(joinKey(path, key), t.Elem(), item, errs)
+		}
+	}
+	return value
+}
+
+// resolveSecret returns the secret a secret reference refers to
+func resolveSecret(reference map[string]interface{}) (string, error) {
+	var sources []string
+	for key := range reference {
+		sources = append(sources, key)
+	}
+	sort.Strings(sources)
+	if len(sources) != 1 {
+		return "", fmt.Errorf("secret reference must have exactly one of the keys %s, got %s", strings.Join(secretSources, ", "), strings.Join(sources, ", "))
+	}
+
+	source, argument := sources[0], reference[sources[0]]
+	switch source {
+	case "file":
+		path, ok := argument.(string)
+		if !ok || path == "" {
+			return "", fmt.Errorf("file of secret reference must be a path")
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("could not read secret: %w", err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), nil
+	case "env":
+		name, ok := argument.(string)
+		if !ok || name == "" {
+			return "", fmt.Errorf("env of secret reference must be the name of an environment variable")
+		}
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case "exec":
+		items, _ := argument.([]interface{})
+		command := make([]string, 0, len(items))
+		for _, item := range items {
+			if arg, ok := item.(string); ok {
+				command = append(command, arg)
+			}
+		}
+		if len(command) == 0 || len(command) != len(items) {
+			return "", fmt.Errorf("exec of secret reference must be a list with the command and its arguments")
+		}
+		return execSecret(command)
+	default:
+		return "", fmt.Errorf("unknown secret reference %s, use one of %s", source, strings.Join(secretSources, ", "))
+	}
+}
+
+// execSecret runs command and returns its output without the trailing newline
+func execSecret(command []string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretExecTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("command %s failed: %w: %s", command[0], err, message)
+		}
+		return "", fmt.Errorf("command %s failed: %w", command[0], err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
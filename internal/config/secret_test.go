@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadResolvesSecretReferences(t *testing.T) {
+	folder := t.TempDir()
+	secretFile := writeSettings(t, t.TempDir(), "trakt", "file-secret\n")
+	writeSettings(t, folder, "settings.yaml", `scrobble:
+  token:
+    env: SCROBBLE_TOKEN
+notifications:
+  - type: gotify
+    url: https://gotify.example.com
+    token:
+      exec: [echo, exec-secret]
+trakt:
+  clientId: id
+  clientSecret:
+    file: `+secretFile+`
+  user: me
+`)
+	t.Setenv("SCROBBLE_TOKEN", "env-secret")
+
+	settings, err := Read(folder, "")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if settings.Trakt.ClientSecret != "file-secret" || settings.Scrobble.Token != "env-secret" || settings.Notifications[0].Token != "exec-secret" {
+		t.Errorf("Read() secrets = %q, %q, %q", settings.Trakt.ClientSecret, settings.Scrobble.Token, settings.Notifications[0].Token)
+	}
+}
+
+func TestReadReportsInvalidSecretReferences(t *testing.T) {
+	folder := t.TempDir()
+	writeSettings(t, folder, "settings.json", `{
+		"scrobble": {"token": {"env": "SERIES_CLEANUP_TEST_MISSING_SECRET"}},
+		"notifications": [{"type": "gotify", "url": "https://gotify.example.com", "token": {"exec": ["false"]}}],
+		"trakt": {"clientId": "id", "clientSecret": {"file": "/nonexistent", "env": "X"}, "user": "me"}
+	}`)
+
+	_, err := Read(folder, "")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Read() error = %v, want ValidationErrors", err)
+	}
+	want := []string{"notifications[0].token", "scrobble.token", "trakt.clientSecret"}
+	if got := validationPaths(errs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() problems = %v, want %v", err, want)
+	}
+}
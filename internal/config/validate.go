@@ -0,0 +1,318 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/oriser/regroup"
+
+	"github.com/bjw-s/series-cleanup/internal/filesystem"
+	"github.com/bjw-s/series-cleanup/internal/notify"
+)
+
+// folderRegexGroups are the named groups of folderRegex that are used
+var folderRegexGroups = []string{"Show", "IMDBID", "TVDBID", "TMDBID", "TraktID", "TraktSlug"}
+
+// ValidationError is a problem with a value in the configuration
+type ValidationError struct {
+	// Path is the key of the value, with the index of list items in brackets
+	Path    string
+	Message string
+}
+
+func (err ValidationError) Error() string {
+	return err.Path + ": " + err.Message
+}
+
+// ValidationErrors are all problems that were found in the configuration
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	problems := make([]string, 0, len(errs))
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+	return "configuration validation failed: " + strings.Join(problems, "; ")
+}
+
+func (errs *ValidationErrors) add(path, format string, args ...interface{}) {
+	*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// orNil returns the problems sorted by path, or nil when there are none
+func (errs ValidationErrors) orNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Path < errs[j].Path
+	})
+	return errs
+}
+
+// unknownKeys reports the keys in value, as read from the settings file, that do not belong to
+// a value of type t. Keys are matched case-insensitively, like they are when they are decoded.
+func unknownKeys(path string, t reflect.Type, value interface{}, errs *ValidationErrors) {
+	switch t.Kind() {
+	case reflect.Struct:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, item := range values {
+			field, ok := fieldByName(t, key)
+			if !ok {
+				// Editors use the $schema key to find the JSON Schema of the settings file
+				if path == "" && key == "$schema" {
+					continue
+				}
+				errs.add(joinKey(path, key), "unknown key")
+				continue
+			}
+			unknownKeys(joinKey(path, key), field.Type, item, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			unknownKeys(fmt.Sprintf("%s[%d]", path, i), t.Elem(), item, errs)
+		}
+	case reflect.Map:
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, item := range values {
+			unknownKeys(joinKey(path, key), t.Elem(), item, errs)
+		}
+	}
+}
+
+// fieldByName returns the exported field of struct type t with the given name in the settings file
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && strings.EqualFold(fieldName(field), name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// validate checks settings and compiles folderRegex. All problems are returned together.
+func validate(settings *Settings) error {
+	var errs ValidationErrors
+
+	validate := validator.New()
+	validate.RegisterTagNameFunc(fieldName)
+	if err := validate.Struct(settings); err != nil {
+		var fieldErrors validator.ValidationErrors
+		if !errors.As(err, &fieldErrors) {
+			return fmt.Errorf("configuration validation failed: %w", err)
+		}
+		for _, fieldError := range fieldErrors {
+			// The namespace starts with the name of the Settings struct
+			_, path, _ := strings.Cut(fieldError.Namespace(), ".")
+			errs.add(path, ruleMessage(fieldError))
+		}
+	}
+
+	for i, notification := range settings.Notifications {
+		if notification.Type == "smtp" && (notification.SMTP.Host == "" || notification.SMTP.From == "" || len(notification.SMTP.To) == 0) {
+			errs.add(fmt.Sprintf("notifications[%d].smtp", i), "type smtp requires smtp.host, smtp.from and smtp.to")
+		}
+		if notification.Type == "webhook" && notification.Body != "" {
+			if _, err := notify.ParseBodyTemplate(notification.Body); err != nil {
+				errs.add(fmt.Sprintf("notifications[%d].body", i), "%v", err)
+			}
+		}
+	}
+
+	if settings.DeleteBasedOn == "firstWatched" && settings.Trakt.WatchedSource != "history" {
+		errs.add("deleteBasedOn", "firstWatched requires trakt.watchedSource history")
+	}
+
+	if settings.FolderRegex != "" {
+		folderRegex, err := compileFolderRegex(settings.FolderRegex)
+		if err != nil {
+			errs.add("folderRegex", "%v", err)
+		}
+		settings.folderRegex = folderRegex
+	}
+
+	checkScanFolders(settings.ScanFolders, &errs)
+	checkOverrides(settings.Overrides, &errs)
+	checkRemotes(settings, &errs)
+
+	return errs.orNil()
+}
+
+// ruleMessage describes a failed validation rule
+func ruleMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "required_if", "required_unless":
+		field, value, _ := strings.Cut(fieldError.Param(), " ")
+		condition := map[string]string{"required_if": "when", "required_unless": "unless"}[fieldError.Tag()]
+		return fmt.Sprintf("is required %s %s is %s", condition, strings.ToLower(field), value)
+	case "oneof":
+		return fmt.Sprintf("must be one of %s, got %q", strings.Join(strings.Fields(fieldError.Param()), ", "), fmt.Sprint(fieldError.Value()))
+	case "gte":
+		return fmt.Sprintf("must be at least %s, got %v", fieldError.Param(), fieldError.Value())
+	case "lte":
+		return fmt.Sprintf("must be at most %s, got %v", fieldError.Param(), fieldError.Value())
+	default:
+		return fmt.Sprintf("does not satisfy the %s rule", fieldError.Tag())
+	}
+}
+
+// compileFolderRegex compiles folderRegex and checks that it only uses known named groups
+func compileFolderRegex(expr string) (*regroup.ReGroup, error) {
+	compiled, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+
+	for _, name := range compiled.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, group := range folderRegexGroups {
+			known = known || name == group
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown group %s, use one of %s", name, strings.Join(folderRegexGroups, ", "))
+		}
+	}
+	return regroup.Compile(expr)
+}
+
+// checkScanFolders checks that the scan folders exist and that none of them is inside another one. Of remote scan folders only the URL
+// is checked, they are not connected to.
+func checkScanFolders(scanFolders []string, errs *ValidationErrors) {
+	for i, scanFolder := range scanFolders {
+		path := fmt.Sprintf("scanFolders[%d]", i)
+		if scanFolder == "" {
+			errs.add(path, "is empty")
+			continue
+		}
+
+		if filesystem.IsRemote(scanFolder) {
+			if _, err := filesystem.ParseLocation(scanFolder); err != nil {
+				errs.add(path, "invalid URL: %v", err)
+				continue
+			}
+		} else if !checkLocalScanFolder(path, scanFolder, errs) {
+			continue
+		}
+
+		for j := 0; j < i; j++ {
+			other := scanFolders[j]
+			switch {
+			case other == "":
+			case filepath.Clean(other) == filepath.Clean(scanFolder):
+				errs.add(path, "is the same folder as scanFolders[%d]", j)
+			case isInside(scanFolder, other):
+				errs.add(path, "folder %s is inside scanFolders[%d] %s", scanFolder, j, other)
+			case isInside(other, scanFolder):
+				errs.add(path, "folder %s contains scanFolders[%d] %s", scanFolder, j, other)
+			}
+		}
+	}
+}
+
+// checkLocalScanFolder checks that a local scan folder exists. It returns false when it does not.
+func checkLocalScanFolder(path, scanFolder string, errs *ValidationErrors) bool {
+	info, err := os.Stat(scanFolder)
+	switch {
+	case os.IsNotExist(err):
+		errs.add(path, "folder %s does not exist", scanFolder)
+		return false
+	case err != nil:
+		errs.add(path, "%v", err)
+		return false
+	case !info.IsDir():
+		errs.add(path, "%s is not a folder", scanFolder)
+		return false
+	}
+	return true
+}
+
+// checkRemotes checks the URLs of the remotes, and that scan folders are only watched for
+// changes when they are local
+func checkRemotes(settings *Settings, errs *ValidationErrors) {
+	for i, remote := range settings.Remotes {
+		if remote.URL != "" && !filesystem.IsRemote(remote.URL) {
+			errs.add(fmt.Sprintf("remotes[%d].url", i), "%s is not a URL such as sftp://nas", remote.URL)
+		}
+	}
+
+	if settings.Daemon.Watch {
+		for _, scanFolder := range settings.ScanFolders {
+			if filesystem.IsRemote(scanFolder) {
+				errs.add("daemon.watch", "remote scan folders can not be watched, use daemon.intervalMinutes instead")
+				break
+			}
+		}
+	}
+}
+
+// CheckScanFoldersWritable checks that files can be removed from the local scan folders. This is
+// not part of the validation, as only the commands that remove files need it.
+// Problems are returned as ValidationErrors.
+func (settings *Settings) CheckScanFoldersWritable() error {
+	var errs ValidationErrors
+	for i, scanFolder := range settings.ScanFolders {
+		if scanFolder == "" || filesystem.IsRemote(scanFolder) {
+			continue
+		}
+		if err := checkWritable(scanFolder); err != nil {
+			errs.add(fmt.Sprintf("scanFolders[%d]", i), "folder %s is not writable: %v", scanFolder, err)
+		}
+	}
+	return errs.orNil()
+}
+
+// checkWritable checks that files can be created in folder, by creating and removing a hidden file
+func checkWritable(folder string) error {
+	file, err := os.CreateTemp(folder, ".series-cleanup-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// isInside indicates if path is inside folder
+func isInside(path, folder string) bool {
+	rel, err := filepath.Rel(folder, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkOverrides checks that every show folder is overridden only once. Folders are matched
+// case-insensitively, like they are when the overrides are applied.
+func checkOverrides(overrides []folderOverride, errs *ValidationErrors) {
+	seen := map[string]int{}
+	for i, override := range overrides {
+		if override.Folder == "" {
+			continue
+		}
+		key := strings.ToLower(override.Folder)
+		if first, ok := seen[key]; ok {
+			errs.add(fmt.Sprintf("overrides[%d].folder", i), "folder %s is already overridden in overrides[%d]", override.Folder, first)
+			continue
+		}
+		seen[key] = i
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadReportsAllProblems(t *testing.T) {
+	folder := t.TempDir()
+	media := t.TempDir()
+	anime := filepath.Join(media, "anime")
+	if err := os.Mkdir(anime, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(media, "missing")
+
+	writeSettings(t, folder, "settings.yaml", `deleteAfterHour: 48
+deleteAfterHours: -1
+folderRegex: "(?P<Name>.*"
+scanFolders:
+  - `+media+`
+  - `+anime+`
+  - `+missing+`
+overrides:
+  - folder: Show
+    skipSeason: [1]
+  - folder: show
+trakt:
+  clientId: id
+  clientSecret: secret
+  user: me
+`)
+
+	// Unknown keys are reported before anything else is checked
+	_, err := Read(folder, "")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Read() error = %v, want ValidationErrors", err)
+	}
+	want := []string{"deleteAfterHour", "overrides[0].skipSeason"}
+	if got := validationPaths(errs); !reflect.DeepEqual(got, want) {
+		t.Errorf("unknown keys = %v, want %v", got, want)
+	}
+
+	settingsFile := filepath.Join(folder, "settings.yaml")
+	content, _ := os.ReadFile(settingsFile)
+	content = []byte(strings.NewReplacer("deleteAfterHour: 48\n", "", "    skipSeason: [1]\n", "").Replace(string(content)))
+	writeSettings(t, folder, "settings.yaml", string(content))
+
+	_, err = Read(folder, "")
+	if !errors.As(err, &errs) {
+		t.Fatalf("Read() error = %v, want ValidationErrors", err)
+	}
+	want = []string{"deleteAfterHours", "folderRegex", "overrides[1].folder", "scanFolders[1]", "scanFolders[2]"}
+	if got := validationPaths(errs); !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() problems = %v, want %v", err, want)
+	}
+}
+
+func TestCompileFolderRegex(t *testing.T) {
+	if _, err := compileFolderRegex(`(?P<Show>.*) \[tvdb-(?P<TVDBID>\d+)\]`); err != nil {
+		t.Errorf("compileFolderRegex() error = %v", err)
+	}
+	if _, err := compileFolderRegex(`(?P<Title>.*)`); err == nil || !strings.Contains(err.Error(), "unknown group Title") {
+		t.Errorf("compileFolderRegex() error = %v, want unknown group", err)
+	}
+}
+
+func TestReadAllowsSchemaKey(t *testing.T) {
+	folder := t.TempDir()
+	writeSettings(t, folder, "settings.json", `{"$schema": "./settings.schema.json", "trakt": {"clientId": "id", "clientSecret": "secret", "user": "me"}}`)
+
+	settings, err := Read(folder, "")
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if settings.CompiledFolderRegex() == nil {
+		t.Error("CompiledFolderRegex() = nil, want the default folderRegex")
+	}
+}
+
+func TestReadRequiresScrobbleToken(t *testing.T) {
+	folder := t.TempDir()
+	writeSettings(t, folder, "settings.json", `{"scrobble": {"enabled": true}, "trakt": {"clientId": "id", "clientSecret": "secret", "user": "me"}}`)
+
+	_, err := Read(folder, "")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || !reflect.DeepEqual(validationPaths(errs), []string{"scrobble.token"}) {
+		t.Errorf("Read() error = %v, want scrobble.token to be required", err)
+	}
+}
+
+func TestReadRequiresApprovalToken(t *testing.T) {
+	folder := t.TempDir()
+	writeSettings(t, folder, "settings.json", `{"approval": {"enabled": true}, "trakt": {"clientId": "id", "clientSecret": "secret", "user": "me"}}`)
+
+	_, err := Read(folder, "")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || !reflect.DeepEqual(validationPaths(errs), []string{"approval.token"}) {
+		t.Errorf("Read() error = %v, want approval.token to be required", err)
+	}
+}
+
+func TestReadReportsInvalidBodyTemplate(t *testing.T) {
+	folder := t.TempDir()
+	writeSettings(t, folder, "settings.json", `{"notifications": [{"type": "webhook", "url": "https://example.com", "body": "{{.Removed"}], "trakt": {"clientId": "id", "clientSecret": "secret", "user": "me"}}`)
+
+	_, err := Read(folder, "")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || !reflect.DeepEqual(validationPaths(errs), []string{"notifications[0].body"}) {
+		t.Errorf("Read() error = %v, want notifications[0].body to be invalid", err)
+	}
+}
+
+func TestCheckScanFoldersWritable(t *testing.T) {
+	settings := &Settings{ScanFolders: []string{t.TempDir(), "sftp://nas/tv", filepath.Join(t.TempDir(), "missing")}}
+
+	err := settings.CheckScanFoldersWritable()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || !reflect.DeepEqual(validationPaths(errs), []string{"scanFolders[2]"}) {
+		t.Errorf("CheckScanFoldersWritable() error = %v, want only the local folder that can't be written to", err)
+	}
+}
+
+func validationPaths(errs ValidationErrors) []string {
+	paths := make([]string, 0, len(errs))
+	for _, err := range errs {
+		paths = append(paths, err.Path)
+	}
+	return paths
+}
@@ -0,0 +1,198 @@
+// Package filesystem implements access to the files in the scan folders, which are local folders
+// or folders on a remote server
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileSystem gives access to the files of a scan folder. Paths are absolute paths in the file system.
+type FileSystem interface {
+	// Stat returns information about the file or folder at path
+	Stat(path string) (fs.FileInfo, error)
+	// ReadDir returns the files and folders in the folder at path, sorted by name
+	ReadDir(path string) ([]fs.FileInfo, error)
+	// Remove removes the file at path
+	Remove(path string) error
+	// Close closes the connection to a remote server
+	Close() error
+}
+
+// Credentials are used to connect to a remote server
+type Credentials struct {
+	Username string
+	Password string
+	// PrivateKeyFile is the path of the private key used to authenticate with an SFTP server
+	PrivateKeyFile string
+	// KnownHostsFile is the path of the known_hosts file used to verify the key of an SFTP server
+	KnownHostsFile string
+	// InsecureIgnoreHostKey disables the verification of the key of an SFTP server
+	InsecureIgnoreHostKey bool
+}
+
+// schemes are the URL schemes of the supported remote servers
+var schemes = []string{"sftp", "webdav", "webdavs"}
+
+// IsRemote indicates if location is the URL of a folder on a remote server instead of a local path
+func IsRemote(location string) bool {
+	return strings.Contains(location, "://")
+}
+
+// ParseLocation checks the URL of a folder on a remote server and returns it
+func ParseLocation(location string) (*url.URL, error) {
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+
+	supported := false
+	for _, scheme := range schemes {
+		supported = supported || parsed.Scheme == scheme
+	}
+	switch {
+	case !supported:
+		return nil, fmt.Errorf("unsupported scheme %s, use one of %s", parsed.Scheme, strings.Join(schemes, ", "))
+	case parsed.Host == "":
+		return nil, errors.New("the host is missing")
+	case !strings.HasPrefix(parsed.Path, "/"):
+		return nil, errors.New("the path of the folder is missing")
+	case parsed.RawQuery != "" || parsed.Fragment != "":
+		return nil, errors.New("a query or fragment is not supported")
+	}
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		return nil, errors.New("do not include the password in the URL, configure it in remotes instead")
+	}
+	return parsed, nil
+}
+
+// Root returns the path of location in its file system
+func Root(location string) string {
+	if !IsRemote(location) {
+		return location
+	}
+	parsed, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	return parsed.Path
+}
+
+// Open opens the file system that contains location, which is a local path or the URL of a
+// folder on a remote server, such as sftp://nas/volume1/Series. It returns the file system
+// and the path of location in it.
+func Open(location string, credentials Credentials) (FileSystem, string, error) {
+	if !IsRemote(location) {
+		return Local{}, location, nil
+	}
+
+	parsed, err := ParseLocation(location)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid location %s: %w", location, err)
+	}
+	if parsed.User != nil && parsed.User.Username() != "" {
+		credentials.Username = parsed.User.Username()
+	}
+
+	var fileSystem FileSystem
+	switch parsed.Scheme {
+	case "sftp":
+		fileSystem, err = openSFTP(parsed.Host, credentials)
+	case "webdav":
+		fileSystem = newWebDAV("http", parsed.Host, credentials)
+	case "webdavs":
+		fileSystem = newWebDAV("https", parsed.Host, credentials)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("could not connect to %s: %w", parsed.Redacted(), err)
+	}
+	return fileSystem, parsed.Path, nil
+}
+
+// Walk walks the file tree rooted at root like filepath.Walk does
+func Walk(fileSystem FileSystem, root string, walkFn filepath.WalkFunc) error {
+	info, err := fileSystem.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walk(fileSystem, root, info, walkFn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walk(fileSystem FileSystem, path string, info fs.FileInfo, walkFn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return walkFn(path, info, nil)
+	}
+
+	entries, err := fileSystem.ReadDir(path)
+	walkErr := walkFn(path, info, err)
+	// When reading the folder failed, walkFn decides whether the walk continues
+	if err != nil || walkErr != nil {
+		return walkErr
+	}
+
+	for _, entry := range entries {
+		if err := walk(fileSystem, filepath.Join(path, entry.Name()), entry, walkFn); err != nil {
+			if !entry.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Local is the local file system
+type Local struct{}
+
+// Stat implements FileSystem
+func (Local) Stat(path string) (fs.FileInfo, error) {
+	return os.Stat(path)
+}
+
+// ReadDir implements FileSystem
+func (Local) ReadDir(path string) ([]fs.FileInfo, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// The file was removed after the folder was read
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Remove implements FileSystem
+func (Local) Remove(path string) error {
+	return os.Remove(path)
+}
+
+// Close implements FileSystem
+func (Local) Close() error {
+	return nil
+}
+
+func sortByName(infos []fs.FileInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+}
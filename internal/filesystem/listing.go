@@ -0,0 +1,51 @@
+package filesystem
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sync"
+)
+
+// ListingCache is a FileSystem that reads every folder of another FileSystem only once, so the
+// walk of a scan and the files in a folder share its listing instead of each reading it again.
+// Removing a file discards the listing of its folder. It is safe for concurrent use.
+type ListingCache struct {
+	FileSystem
+	mutex    sync.Mutex
+	listings map[string][]fs.FileInfo
+}
+
+// NewListingCache creates a new ListingCache instance for fileSystem
+func NewListingCache(fileSystem FileSystem) *ListingCache {
+	return &ListingCache{
+		FileSystem: fileSystem,
+		listings:   map[string][]fs.FileInfo{},
+	}
+}
+
+// ReadDir implements FileSystem. Failures are not cached, so the folder is read again next time.
+func (cache *ListingCache) ReadDir(path string) ([]fs.FileInfo, error) {
+	cache.mutex.Lock()
+	listing, ok := cache.listings[path]
+	cache.mutex.Unlock()
+	if ok {
+		return listing, nil
+	}
+
+	listing, err := cache.FileSystem.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	cache.mutex.Lock()
+	cache.listings[path] = listing
+	cache.mutex.Unlock()
+	return listing, nil
+}
+
+// Remove implements FileSystem
+func (cache *ListingCache) Remove(path string) error {
+	cache.mutex.Lock()
+	delete(cache.listings, filepath.Dir(path))
+	cache.mutex.Unlock()
+	return cache.FileSystem.Remove(path)
+}
@@ -0,0 +1,116 @@
+package filesystem
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is a file system that is kept in memory, for testing. Folders are created implicitly
+// when a file is added to them.
+type Memory struct {
+	mutex sync.Mutex
+	// files contains the files and folders by their clean path
+	files map[string]*fileInfo
+}
+
+// NewMemory returns an empty Memory file system, which only contains the root folder
+func NewMemory() *Memory {
+	return &Memory{files: map[string]*fileInfo{
+		"/": {name: "/", dir: true},
+	}}
+}
+
+// AddFile adds a file with the given size and modification time at path, and the folders it is in
+func (memory *Memory) AddFile(path string, size int64, modTime time.Time) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	path = filepath.Clean("/" + path)
+	memory.files[path] = &fileInfo{name: filepath.Base(path), size: size, modTime: modTime}
+	for folder := filepath.Dir(path); memory.files[folder] == nil; folder = filepath.Dir(folder) {
+		memory.files[folder] = &fileInfo{name: filepath.Base(folder), modTime: modTime, dir: true}
+	}
+}
+
+// Files returns the paths of all files, without the folders, sorted by path
+func (memory *Memory) Files() []string {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	var paths []string
+	for path, info := range memory.files {
+		if !info.dir {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Stat implements FileSystem
+func (memory *Memory) Stat(path string) (fs.FileInfo, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	info, ok := memory.files[filepath.Clean(path)]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: path, Err: fs.ErrNotExist}
+	}
+	copied := *info
+	return &copied, nil
+}
+
+// ReadDir implements FileSystem
+func (memory *Memory) ReadDir(path string) ([]fs.FileInfo, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	path = filepath.Clean(path)
+	info, ok := memory.files[path]
+	switch {
+	case !ok:
+		return nil, &fs.PathError{Op: "readdir", Path: path, Err: fs.ErrNotExist}
+	case !info.dir:
+		return nil, &fs.PathError{Op: "readdir", Path: path, Err: fs.ErrInvalid}
+	}
+
+	var infos []fs.FileInfo
+	for other, info := range memory.files {
+		if other != path && filepath.Dir(other) == path {
+			copied := *info
+			infos = append(infos, &copied)
+		}
+	}
+	sortByName(infos)
+	return infos, nil
+}
+
+// Remove implements FileSystem. Like os.Remove, it only removes empty folders.
+func (memory *Memory) Remove(path string) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	path = filepath.Clean(path)
+	info, ok := memory.files[path]
+	if !ok {
+		return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrNotExist}
+	}
+	if info.dir {
+		for other := range memory.files {
+			if strings.HasPrefix(other, path+"/") || (path == "/" && other != "/") {
+				return &fs.PathError{Op: "remove", Path: path, Err: fs.ErrExist}
+			}
+		}
+	}
+	delete(memory.files, path)
+	return nil
+}
+
+// Close implements FileSystem
+func (memory *Memory) Close() error {
+	return nil
+}
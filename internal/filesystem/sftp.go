@@ -0,0 +1,105 @@
+package filesystem
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// dialTimeout is how long connecting to a remote server may take
+const dialTimeout = 30 * time.Second
+
+// sftpFileSystem is a file system on an SFTP server
+type sftpFileSystem struct {
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+func openSFTP(host string, credentials Credentials) (*sftpFileSystem, error) {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "22")
+	}
+
+	var auth []ssh.AuthMethod
+	if credentials.PrivateKeyFile != "" {
+		key, err := os.ReadFile(credentials.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read private key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if credentials.Password != "" {
+		auth = append(auth, ssh.Password(credentials.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("a password or private key is required")
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case credentials.KnownHostsFile != "":
+		callback, err := knownhosts.New(credentials.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read known hosts: %w", err)
+		}
+		hostKeyCallback = callback
+	case credentials.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, errors.New("a known hosts file is required to verify the server")
+	}
+
+	conn, err := ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            credentials.Username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         dialTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &sftpFileSystem{conn: conn, client: client}, nil
+}
+
+// Stat implements FileSystem
+func (fileSystem *sftpFileSystem) Stat(path string) (fs.FileInfo, error) {
+	return fileSystem.client.Stat(path)
+}
+
+// ReadDir implements FileSystem
+func (fileSystem *sftpFileSystem) ReadDir(path string) ([]fs.FileInfo, error) {
+	infos, err := fileSystem.client.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	sortByName(infos)
+	return infos, nil
+}
+
+// Remove implements FileSystem
+func (fileSystem *sftpFileSystem) Remove(path string) error {
+	return fileSystem.client.Remove(path)
+}
+
+// Close implements FileSystem
+func (fileSystem *sftpFileSystem) Close() error {
+	fileSystem.client.Close()
+	return fileSystem.conn.Close()
+}
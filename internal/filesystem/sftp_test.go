@@ -0,0 +1,129 @@
+package filesystem
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// startSFTPServer starts an SFTP server on localhost that accepts the user test with the password
+// secret, and returns its address and the path of a known_hosts file with its key
+func startSFTPServer(t *testing.T) (string, string) {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostKey, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "test" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("access denied")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, config)
+		}
+	}()
+
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(listener.Addr().String())}, hostKey.PublicKey())
+	if err := os.WriteFile(knownHosts, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return listener.Addr().String(), knownHosts
+}
+
+func serveSFTP(conn net.Conn, config *ssh.ServerConfig) {
+	_, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			for request := range channelRequests {
+				ok := request.Type == "subsystem" && strings.HasSuffix(string(request.Payload), "sftp")
+				request.Reply(ok, nil)
+				if ok {
+					server, err := sftp.NewServer(channel)
+					if err == nil {
+						server.Serve()
+					}
+					channel.Close()
+				}
+			}
+		}()
+	}
+}
+
+func TestSFTP(t *testing.T) {
+	address, knownHosts := startSFTPServer(t)
+	root := t.TempDir()
+	createTree(t, root, "Show/Season 1/Show.S01E01.mkv", "Show/Season 1/Show.S01E01.srt", "Show/Season 1/Show.S01E02.mkv", "Other/Season 2/Other.S02E01.mp4")
+
+	fileSystem, path, err := Open("sftp://test@"+address+root, Credentials{Password: "secret", KnownHostsFile: knownHosts})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer fileSystem.Close()
+	if path != root {
+		t.Errorf("Open() path = %v, want %v", path, root)
+	}
+	testFileSystem(t, fileSystem, root)
+}
+
+func TestSFTPRejectsUnknownHostAndWrongPassword(t *testing.T) {
+	address, knownHosts := startSFTPServer(t)
+	otherKnownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(otherKnownHosts, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := Open("sftp://test@"+address+"/", Credentials{Password: "secret", KnownHostsFile: otherKnownHosts}); err == nil {
+		t.Error("Open() with an unknown host key succeeded")
+	}
+	if _, _, err := Open("sftp://test@"+address+"/", Credentials{Password: "wrong", KnownHostsFile: knownHosts}); err == nil {
+		t.Error("Open() with a wrong password succeeded")
+	}
+	if _, _, err := Open("sftp://test@"+address+"/", Credentials{Password: "secret"}); err == nil {
+		t.Error("Open() without host key verification succeeded")
+	}
+}
@@ -0,0 +1,195 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// propfindBody requests the properties of the files that are used
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/><D:getlastmodified/></D:prop></D:propfind>`
+
+// webDAVFileSystem is a file system on a WebDAV server
+type webDAVFileSystem struct {
+	scheme      string
+	host        string
+	credentials Credentials
+	client      *http.Client
+}
+
+func newWebDAV(scheme, host string, credentials Credentials) *webDAVFileSystem {
+	return &webDAVFileSystem{
+		scheme:      scheme,
+		host:        host,
+		credentials: credentials,
+		client:      &http.Client{Timeout: dialTimeout},
+	}
+}
+
+// multistatus is the response to a PROPFIND request
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"href"`
+		Propstats []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ResourceType struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+				ContentLength int64  `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// fileInfo describes a file on a WebDAV server
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (info *fileInfo) Name() string       { return info.name }
+func (info *fileInfo) Size() int64        { return info.size }
+func (info *fileInfo) ModTime() time.Time { return info.modTime }
+func (info *fileInfo) IsDir() bool        { return info.dir }
+func (info *fileInfo) Sys() interface{}   { return nil }
+
+func (info *fileInfo) Mode() fs.FileMode {
+	if info.dir {
+		return fs.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+func (fileSystem *webDAVFileSystem) request(method, filePath string, depth string, body io.Reader) (*http.Response, error) {
+	requestURL := (&url.URL{Scheme: fileSystem.scheme, Host: fileSystem.host, Path: filePath}).String()
+	request, err := http.NewRequestWithContext(context.Background(), method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if depth != "" {
+		request.Header.Set("Depth", depth)
+		request.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	}
+	if fileSystem.credentials.Username != "" {
+		request.SetBasicAuth(fileSystem.credentials.Username, fileSystem.credentials.Password)
+	}
+	return fileSystem.client.Do(request)
+}
+
+// propfind returns the properties of the file at filePath, followed by those of the files in it
+// when it is a folder and depth is 1
+func (fileSystem *webDAVFileSystem) propfind(op, filePath, depth string) ([]fs.FileInfo, error) {
+	response, err := fileSystem.request("PROPFIND", filePath, depth, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: filePath, Err: err}
+	}
+	defer response.Body.Close()
+
+	if err := statusError(response); err != nil {
+		return nil, &fs.PathError{Op: op, Path: filePath, Err: err}
+	}
+
+	var status multistatus
+	if err := xml.NewDecoder(response.Body).Decode(&status); err != nil {
+		return nil, &fs.PathError{Op: op, Path: filePath, Err: fmt.Errorf("invalid response: %w", err)}
+	}
+
+	var self fs.FileInfo
+	var infos []fs.FileInfo
+	for _, item := range status.Responses {
+		href, err := url.Parse(item.Href)
+		if err != nil {
+			continue
+		}
+		info := &fileInfo{name: path.Base(strings.TrimSuffix(href.Path, "/"))}
+		for _, propstat := range item.Propstats {
+			if !strings.Contains(propstat.Status, " 200 ") {
+				continue
+			}
+			info.dir = propstat.Prop.ResourceType.Collection != nil
+			info.size = propstat.Prop.ContentLength
+			if modTime, err := http.ParseTime(propstat.Prop.LastModified); err == nil {
+				info.modTime = modTime
+			}
+		}
+
+		if strings.TrimSuffix(href.Path, "/") == strings.TrimSuffix(filePath, "/") {
+			self = info
+		} else {
+			infos = append(infos, info)
+		}
+	}
+	if self == nil {
+		return nil, &fs.PathError{Op: op, Path: filePath, Err: fmt.Errorf("invalid response: %s is missing", filePath)}
+	}
+	return append([]fs.FileInfo{self}, infos...), nil
+}
+
+// Stat implements FileSystem
+func (fileSystem *webDAVFileSystem) Stat(filePath string) (fs.FileInfo, error) {
+	infos, err := fileSystem.propfind("stat", filePath, "0")
+	if err != nil {
+		return nil, err
+	}
+	return infos[0], nil
+}
+
+// ReadDir implements FileSystem
+func (fileSystem *webDAVFileSystem) ReadDir(filePath string) ([]fs.FileInfo, error) {
+	infos, err := fileSystem.propfind("readdir", filePath, "1")
+	if err != nil {
+		return nil, err
+	}
+	if !infos[0].IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: filePath, Err: fmt.Errorf("not a folder")}
+	}
+	infos = infos[1:]
+	sortByName(infos)
+	return infos, nil
+}
+
+// Remove implements FileSystem
+func (fileSystem *webDAVFileSystem) Remove(filePath string) error {
+	response, err := fileSystem.request(http.MethodDelete, filePath, "", nil)
+	if err != nil {
+		return &fs.PathError{Op: "remove", Path: filePath, Err: err}
+	}
+	defer response.Body.Close()
+
+	if err := statusError(response); err != nil {
+		return &fs.PathError{Op: "remove", Path: filePath, Err: err}
+	}
+	return nil
+}
+
+// Close implements FileSystem
+func (fileSystem *webDAVFileSystem) Close() error {
+	fileSystem.client.CloseIdleConnections()
+	return nil
+}
+
+// statusError returns the error for the status code of a response
+func statusError(response *http.Response) error {
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return fs.ErrNotExist
+	case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+		return fs.ErrPermission
+	case response.StatusCode >= 300:
+		return fmt.Errorf("unexpected status code %d", response.StatusCode)
+	default:
+		return nil
+	}
+}
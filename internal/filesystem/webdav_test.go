@@ -0,0 +1,66 @@
+package filesystem
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/webdav"
+)
+
+// startWebDAVServer starts a WebDAV server that serves root below /dav, and accepts the user test
+// with the password secret
+func startWebDAVServer(t *testing.T, root string) string {
+	t.Helper()
+
+	handler := &webdav.Handler{
+		Prefix:     "/dav",
+		FileSystem: webdav.Dir(root),
+		LockSystem: webdav.NewMemLS(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "test" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func TestWebDAV(t *testing.T) {
+	root := t.TempDir()
+	createTree(t, root, "Show/Season 1/Show.S01E01.mkv", "Show/Season 1/Show.S01E01.srt", "Show/Season 1/Show.S01E02.mkv", "Other/Season 2/Other.S02E01.mp4")
+	host := startWebDAVServer(t, root)
+
+	fileSystem, path, err := Open("webdav://test@"+host+"/dav", Credentials{Password: "secret"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer fileSystem.Close()
+	if path != "/dav" {
+		t.Errorf("Open() path = %v, want /dav", path)
+	}
+	testFileSystem(t, fileSystem, path)
+}
+
+func TestWebDAVErrors(t *testing.T) {
+	host := startWebDAVServer(t, t.TempDir())
+
+	fileSystem, _, err := Open("webdav://test@"+host+"/dav", Credentials{Password: "wrong"})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := fileSystem.Stat("/dav"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Stat() with a wrong password error = %v, want fs.ErrPermission", err)
+	}
+
+	fileSystem, _, _ = Open("webdav://test@"+host+"/dav", Credentials{Password: "secret"})
+	if _, err := fileSystem.Stat("/dav/missing"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat() of a missing file error = %v, want fs.ErrNotExist", err)
+	}
+}
@@ -2,6 +2,7 @@
 package helpers
 
 import (
+	"fmt"
 	"os"
 )
 
@@ -22,3 +23,17 @@ func FolderExists(foldername string) bool {
 	}
 	return info.IsDir()
 }
+
+// FormatBytes returns a human-readable representation of a number of bytes
+func FormatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// dynamicCore writes to a core that can be replaced, so loggers created from it follow
+// changes to the configuration
+type dynamicCore struct {
+	current atomic.Pointer[zapcore.Core]
+	// parent is the root core of a core created by With, whose fields are added to every entry
+	parent *dynamicCore
+	fields []zapcore.Field
+}
+
+func (core *dynamicCore) set(inner zapcore.Core) {
+	core.current.Store(&inner)
+}
+
+func (core *dynamicCore) inner() zapcore.Core {
+	if core.parent != nil {
+		return (*core.parent.current.Load()).With(core.fields)
+	}
+	return *core.current.Load()
+}
+
+func (core *dynamicCore) Enabled(level zapcore.Level) bool {
+	return core.inner().Enabled(level)
+}
+
+func (core *dynamicCore) With(fields []zapcore.Field) zapcore.Core {
+	parent := core
+	if core.parent != nil {
+		parent = core.parent
+	}
+	return &dynamicCore{
+		parent: parent,
+		fields: append(append([]zapcore.Field(nil), core.fields...), fields...),
+	}
+}
+
+func (core *dynamicCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return core.inner().Check(entry, checked)
+}
+
+func (core *dynamicCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return core.inner().Write(entry, fields)
+}
+
+func (core *dynamicCore) Sync() error {
+	return core.inner().Sync()
+}
+
+// levelCore filters the entries written to a core by the level of the component that logged them
+type levelCore struct {
+	zapcore.Core
+	defaultLevel zapcore.Level
+	levels       map[string]zapcore.Level
+	// minimum is the lowest of all levels
+	minimum zapcore.Level
+}
+
+func newLevelCore(inner zapcore.Core, defaultLevel zapcore.Level, levels map[string]zapcore.Level) *levelCore {
+	minimum := defaultLevel
+	for _, level := range levels {
+		if level < minimum {
+			minimum = level
+		}
+	}
+	return &levelCore{Core: inner, defaultLevel: defaultLevel, levels: levels, minimum: minimum}
+}
+
+// levelFor returns the level of the logger with the given name. The level of a component also
+// applies to the loggers named below it, such as trakt.cache for trakt.
+func (core *levelCore) levelFor(name string) zapcore.Level {
+	for name != "" {
+		if level, ok := core.levels[name]; ok {
+			return level
+		}
+		index := strings.LastIndex(name, ".")
+		if index < 0 {
+			break
+		}
+		name = name[:index]
+	}
+	return core.defaultLevel
+}
+
+func (core *levelCore) Enabled(level zapcore.Level) bool {
+	return level >= core.minimum
+}
+
+func (core *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: core.Core.With(fields), defaultLevel: core.defaultLevel, levels: core.levels, minimum: core.minimum}
+}
+
+func (core *levelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < core.levelFor(entry.LoggerName) {
+		return checked
+	}
+	return core.Core.Check(entry, checked)
+}
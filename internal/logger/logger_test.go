@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestComponentLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "series-cleanup.log")
+	t.Cleanup(func() {
+		if err := Configure(DefaultOptions); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	trakt := Named("trakt")
+	traktCache := trakt.Named("cache")
+	watch := Named("watch")
+
+	err := Configure(Options{
+		Format: FormatConsole,
+		Level:  "info",
+		Levels: map[string]string{"trakt": "debug", "watch": "error"},
+		File:   path,
+	})
+	if err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	Debug("main debug")
+	Info("main info")
+	trakt.Debug("trakt debug")
+	traktCache.Debug("trakt cache debug")
+	watch.Info("watch info")
+	watch.Error("watch error")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, message := range []string{"main info", "trakt debug", "trakt cache debug", "watch error"} {
+		if !strings.Contains(string(content), `"msg":"`+message+`"`) {
+			t.Errorf("log file does not contain %q:\n%s", message, content)
+		}
+	}
+	for _, message := range []string{"main debug", "watch info"} {
+		if strings.Contains(string(content), message) {
+			t.Errorf("log file contains %q:\n%s", message, content)
+		}
+	}
+	if !strings.Contains(string(content), `"logger":"trakt.cache"`) {
+		t.Errorf("log file does not contain the component name:\n%s", content)
+	}
+}
+
+func TestConfigureRejectsInvalidLevels(t *testing.T) {
+	if err := Configure(Options{Level: "verbose"}); err == nil {
+		t.Error("Configure() with an invalid level succeeded")
+	}
+	if err := Configure(Options{Level: "info", Levels: map[string]string{"trakt": "verbose"}}); err == nil {
+		t.Error("Configure() with an invalid component level succeeded")
+	}
+}
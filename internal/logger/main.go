@@ -2,37 +2,135 @@
 package logger
 
 import (
+	"fmt"
 	"os"
+	"reflect"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+
+	"github.com/bjw-s/series-cleanup/internal/rotatingfile"
+)
+
+// Formats of the log output
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
 )
 
-var zapLog *zap.Logger
-var atomicLevel zap.AtomicLevel
+// Options configure the log output
+type Options struct {
+	// Format is the format of the output to stdout, FormatJSON or FormatConsole.
+	// The console format is colored when stdout is a terminal.
+	Format string
+	// Level is the minimum level of the messages that are logged
+	Level string
+	// Levels override Level for components, which are the names of named loggers
+	Levels map[string]string
+	// File is the path of a file the messages are written to as well, in JSON
+	File string
+	// MaxSizeMB is the size at which the file is rotated, 0 disables rotation
+	MaxSizeMB int
+	// MaxBackups is the number of rotated files to keep
+	MaxBackups int
+}
+
+// DefaultOptions are the options used until Configure is called
+var DefaultOptions = Options{Format: FormatJSON, Level: "info"}
+
+var (
+	root      = &dynamicCore{}
+	zapLog    = zap.New(root)
+	configure sync.Mutex
+	// configured, options and logFile describe the current configuration
+	configured bool
+	options    Options
+	logFile    *rotatingfile.File
+)
 
 func init() {
-	encoderConfig := zap.NewProductionEncoderConfig()
-	encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout(time.RFC3339)
-	encoderConfig.StacktraceKey = ""
-	atomicLevel = zap.NewAtomicLevel()
-
-	zapLog = zap.New(zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
-		zapcore.Lock(os.Stdout),
-		atomicLevel,
-	))
-	defer zapLog.Sync()
-}
-
-// SetLevel sets the zap log level
-func SetLevel(l string) {
-	loglevel, err := zap.ParseAtomicLevel(l)
+	if err := Configure(DefaultOptions); err != nil {
+		panic(err)
+	}
+}
+
+// Configure replaces the log output. Loggers that were created before keep working,
+// and use the new output from then on.
+func Configure(newOptions Options) error {
+	configure.Lock()
+	defer configure.Unlock()
+
+	if configured && reflect.DeepEqual(options, newOptions) {
+		return nil
+	}
+
+	defaultLevel, err := zapcore.ParseLevel(newOptions.Level)
 	if err != nil {
-		return
+		return fmt.Errorf("invalid log level %q: %w", newOptions.Level, err)
+	}
+	levels := map[string]zapcore.Level{}
+	for component, level := range newOptions.Levels {
+		if levels[component], err = zapcore.ParseLevel(level); err != nil {
+			return fmt.Errorf("invalid log level %q for %s: %w", level, component, err)
+		}
+	}
+
+	cores := []zapcore.Core{
+		zapcore.NewCore(stdoutEncoder(newOptions.Format), zapcore.Lock(os.Stdout), zapcore.DebugLevel),
+	}
+	var file *rotatingfile.File
+	if newOptions.File != "" {
+		if file, err = rotatingfile.Open(newOptions.File, int64(newOptions.MaxSizeMB)*1024*1024, newOptions.MaxBackups); err != nil {
+			return fmt.Errorf("could not open log file: %w", err)
+		}
+		cores = append(cores, zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig()), file, zapcore.DebugLevel))
 	}
-	atomicLevel.SetLevel(loglevel.Level())
+
+	root.set(newLevelCore(zapcore.NewTee(cores...), defaultLevel, levels))
+
+	if logFile != nil {
+		logFile.Close()
+	}
+	configured, options, logFile = true, newOptions, file
+	return nil
+}
+
+func encoderConfig() zapcore.EncoderConfig {
+	config := zap.NewProductionEncoderConfig()
+	config.EncodeTime = zapcore.TimeEncoderOfLayout(time.RFC3339)
+	config.StacktraceKey = ""
+	return config
+}
+
+func stdoutEncoder(format string) zapcore.Encoder {
+	config := encoderConfig()
+	if format != FormatConsole {
+		return zapcore.NewJSONEncoder(config)
+	}
+
+	config.EncodeLevel = zapcore.CapitalLevelEncoder
+	if isTerminal(os.Stdout) {
+		config.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	}
+	return zapcore.NewConsoleEncoder(config)
+}
+
+// isTerminal indicates if file is a terminal
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// L returns the logger. It is meant to be passed to packages that log.
+func L() *zap.Logger {
+	return zapLog
+}
+
+// Named returns the logger of a component, whose level can be configured separately
+func Named(component string) *zap.Logger {
+	return zapLog.Named(component)
 }
 
 // Info logs a message at level Info on the zap logger
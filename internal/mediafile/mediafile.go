@@ -1,12 +1,13 @@
 package mediafile
 
 import (
-	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/samber/lo"
+
+	"github.com/bjw-s/series-cleanup/internal/filesystem"
 )
 
 var mediaFileExtensions = []string{".avi", ".mkv", ".mp4"}
@@ -14,16 +15,20 @@ var subtitleFileExtensions = []string{".srt"}
 
 // MediaFile represents a media file on disk
 type MediaFile struct {
+	fs            filesystem.FileSystem
 	path          string
 	Dir           string
 	Filename      string
 	Extension     string
 	subtitleFiles []string
+	size          int64
+	modTime       time.Time
 }
 
-// NewMediaFile creates a new MediaFile instance
-func NewMediaFile(path string) (*MediaFile, error) {
+// NewMediaFile creates a new MediaFile instance for the file at path in fileSystem
+func NewMediaFile(fileSystem filesystem.FileSystem, path string) (*MediaFile, error) {
 	mediafile := new(MediaFile)
+	mediafile.fs = fileSystem
 	mediafile.path = path
 	err := mediafile.getBasicFileData()
 	if err != nil {
@@ -46,7 +51,7 @@ func (mediafile *MediaFile) getBasicFileData() error {
 
 func (mediafile *MediaFile) getSubtitleFiles() error {
 	mediaFileBaseName := strings.TrimSuffix(mediafile.Filename, mediafile.Extension)
-	filesInSameFolder, err := os.ReadDir(mediafile.Dir)
+	filesInSameFolder, err := mediafile.fs.ReadDir(mediafile.Dir)
 	if err != nil {
 		return err
 	}
@@ -56,7 +61,13 @@ func (mediafile *MediaFile) getSubtitleFiles() error {
 			continue
 		}
 
-		if lo.Contains(subtitleFileExtensions, strings.ToLower(filepath.Ext(file.Name()))) {
+		if file.Name() == mediafile.Filename {
+			mediafile.size += file.Size()
+			mediafile.modTime = file.ModTime()
+			continue
+		}
+
+		if !lo.Contains(subtitleFileExtensions, strings.ToLower(filepath.Ext(file.Name()))) {
 			continue
 		}
 
@@ -65,13 +76,33 @@ func (mediafile *MediaFile) getSubtitleFiles() error {
 		}
 
 		mediafile.subtitleFiles = append(mediafile.subtitleFiles, file.Name())
+		mediafile.size += file.Size()
 	}
 	return nil
 }
 
+// Size returns the combined size in bytes of the media file and its subtitle files
+func (mediafile *MediaFile) Size() int64 {
+	return mediafile.size
+}
+
+// SubtitleFiles returns the paths of the subtitle files belonging to the media file
+func (mediafile *MediaFile) SubtitleFiles() []string {
+	paths := make([]string, 0, len(mediafile.subtitleFiles))
+	for _, subtitleFile := range mediafile.subtitleFiles {
+		paths = append(paths, filepath.Join(mediafile.Dir, subtitleFile))
+	}
+	return paths
+}
+
+// ModTime returns when the media file was last modified
+func (mediafile *MediaFile) ModTime() time.Time {
+	return mediafile.modTime
+}
+
 // Delete will remove the media file from disk
 func (mediafile *MediaFile) Delete() error {
-	err := os.Remove(mediafile.path)
+	err := mediafile.fs.Remove(mediafile.path)
 	if err != nil {
 		return err
 	}
@@ -82,7 +113,7 @@ func (mediafile *MediaFile) Delete() error {
 // DeleteWithSubtitleFiles will remove the media file from disk along with all subtitle files
 func (mediafile *MediaFile) DeleteWithSubtitleFiles() error {
 	for _, subtitleFile := range mediafile.subtitleFiles {
-		err := os.Remove(fmt.Sprintf("%v/%v", mediafile.Dir, subtitleFile))
+		err := mediafile.fs.Remove(filepath.Join(mediafile.Dir, subtitleFile))
 		if err != nil {
 			return err
 		}
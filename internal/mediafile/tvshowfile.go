@@ -1,11 +1,14 @@
 package mediafile
 
 import (
+	"fmt"
 	"path/filepath"
 	"regexp"
 	"strconv"
 
 	"github.com/oriser/regroup"
+
+	"github.com/bjw-s/series-cleanup/internal/filesystem"
 )
 
 // TVShowFile represents a tv show file on disk
@@ -18,46 +21,71 @@ type TVShowFile struct {
 	Mappings ShowMapping
 }
 
+var (
+	seasonRegex  = regexp.MustCompile(`.*[sS](\d+)[eE]\d+.*`)
+	episodeRegex = regexp.MustCompile(`.*[sS]\d+[eE](\d+).*`)
+)
+
 // ShowMapping contain any mappings that need to be done for a show
 type ShowMapping struct {
-	TraktName string
-	TVDBID    int
-	IMDBID    string
+	TraktName string `json:"traktName,omitempty"`
+	TraktID   int    `json:"traktId,omitempty"`
+	TraktSlug string `json:"traktSlug,omitempty"`
+	TVDBID    int    `json:"tvdbId,omitempty"`
+	IMDBID    string `json:"imdbId,omitempty"`
+	TMDBID    int    `json:"tmdbId,omitempty"`
 }
 
-func (tvShowFile *TVShowFile) determineShow(regex string) error {
+func (tvShowFile *TVShowFile) determineShow(folderRegex *regroup.ReGroup) error {
 	show := filepath.Base(filepath.Dir(tvShowFile.Dir))
-	if len(regex) > 0 {
-		folderRegex := regroup.MustCompile(regex)
+	if folderRegex != nil {
 		matches, _ := folderRegex.Groups(show)
 
 		if matchShow, ok := matches["Show"]; ok {
 			show = matchShow
 		}
 
-		if matchIMDBID, ok := matches["IMDBID"]; ok {
+		if matchIMDBID, ok := matches["IMDBID"]; ok && matchIMDBID != "" {
 			tvShowFile.Mappings.IMDBID = matchIMDBID
 		}
 
-		if matchTVBID, ok := matches["TVDBID"]; ok {
+		if matchTVBID, ok := matches["TVDBID"]; ok && matchTVBID != "" {
 			tvdbID, err := strconv.Atoi(matchTVBID)
 			if err != nil {
 				return err
 			}
 			tvShowFile.Mappings.TVDBID = tvdbID
 		}
+
+		if matchTMDBID, ok := matches["TMDBID"]; ok && matchTMDBID != "" {
+			tmdbID, err := strconv.Atoi(matchTMDBID)
+			if err != nil {
+				return err
+			}
+			tvShowFile.Mappings.TMDBID = tmdbID
+		}
+
+		if matchTraktID, ok := matches["TraktID"]; ok && matchTraktID != "" {
+			traktID, err := strconv.Atoi(matchTraktID)
+			if err != nil {
+				return err
+			}
+			tvShowFile.Mappings.TraktID = traktID
+		}
+
+		if matchTraktSlug, ok := matches["TraktSlug"]; ok && matchTraktSlug != "" {
+			tvShowFile.Mappings.TraktSlug = matchTraktSlug
+		}
 	}
 	tvShowFile.Show = show
 	return nil
 }
 
 func (tvShowFile *TVShowFile) determineSeason() error {
-	r, err := regexp.Compile(`.*[sS](\d+)[eE]\d+.*`)
-	if err != nil {
-		return err
+	result := seasonRegex.FindAllStringSubmatch(tvShowFile.Filename, -1)
+	if len(result) == 0 {
+		return fmt.Errorf("could not determine season number from %v", tvShowFile.Filename)
 	}
-
-	result := r.FindAllStringSubmatch(tvShowFile.Filename, -1)
 	season, err := strconv.Atoi(result[0][1])
 	if err != nil {
 		return err
@@ -67,12 +95,10 @@ func (tvShowFile *TVShowFile) determineSeason() error {
 }
 
 func (tvShowFile *TVShowFile) determineEpisode() error {
-	r, err := regexp.Compile(`.*[sS]\d+[eE](\d+).*`)
-	if err != nil {
-		return err
+	result := episodeRegex.FindAllStringSubmatch(tvShowFile.Filename, -1)
+	if len(result) == 0 {
+		return fmt.Errorf("could not determine episode number from %v", tvShowFile.Filename)
 	}
-
-	result := r.FindAllStringSubmatch(tvShowFile.Filename, -1)
 	episode, err := strconv.Atoi(result[0][1])
 	if err != nil {
 		return err
@@ -81,9 +107,11 @@ func (tvShowFile *TVShowFile) determineEpisode() error {
 	return nil
 }
 
-// NewTVShowFile creates a new MediaFile instance
-func NewTVShowFile(path string, folderRegex string) (*TVShowFile, error) {
+// NewTVShowFile creates a new MediaFile instance for the file at path in fileSystem. The show is
+// determined by folderRegex when it is not nil, otherwise it is the name of the show folder.
+func NewTVShowFile(fileSystem filesystem.FileSystem, path string, folderRegex *regroup.ReGroup) (*TVShowFile, error) {
 	tvshowfile := new(TVShowFile)
+	tvshowfile.fs = fileSystem
 	tvshowfile.path = path
 	err := tvshowfile.getBasicFileData()
 	if err != nil {
@@ -0,0 +1,135 @@
+// Package metrics implements the Prometheus metrics of series-cleanup
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "series_cleanup"
+
+var (
+	filesScanned = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "files_scanned_total",
+		Help:      "Number of media files found in the scan folders.",
+	}, []string{"folder"})
+
+	filesParsed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "files_parsed_total",
+		Help:      "Number of media files that were recognized as a TV show episode.",
+	}, []string{"folder"})
+
+	filesUnrecognized = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "files_unrecognized_total",
+		Help:      "Number of media files that could not be recognized as a TV show episode.",
+	}, []string{"folder"})
+
+	candidates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "candidates_total",
+		Help:      "Number of TV show files that were eligible for removal.",
+	}, []string{"folder"})
+
+	fileActions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "file_actions_total",
+		Help:      "Number of TV show files by the action that was taken and the reason for it.",
+	}, []string{"folder", "action", "reason"})
+
+	bytesFreed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "freed_bytes_total",
+		Help:      "Number of bytes freed by removing TV show files and their subtitle files.",
+	}, []string{"folder"})
+
+	traktRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "trakt_request_duration_seconds",
+		Help:      "Latency of requests to the Trakt API.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "status_code"})
+
+	traktTokenExpiry = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "trakt_token_expiry_timestamp_seconds",
+		Help:      "Unix timestamp at which the Trakt access token expires.",
+	})
+
+	lastSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix timestamp of the last run that completed without errors.",
+	})
+
+	runDuration = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of the last run.",
+	})
+)
+
+// Handler returns the HTTP handler that exposes the metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// FileScanned registers that a media file was found in a scan folder
+func FileScanned(folder string) {
+	filesScanned.WithLabelValues(folder).Inc()
+}
+
+// FileParsed registers that a media file was recognized as a TV show episode
+func FileParsed(folder string) {
+	filesParsed.WithLabelValues(folder).Inc()
+}
+
+// FileUnrecognized registers that a media file could not be recognized as a TV show episode
+func FileUnrecognized(folder string) {
+	filesUnrecognized.WithLabelValues(folder).Inc()
+}
+
+// Candidate registers that a TV show file was eligible for removal
+func Candidate(folder string) {
+	candidates.WithLabelValues(folder).Inc()
+}
+
+// FileAction registers the action that was taken for a TV show file and the reason for it
+func FileAction(folder string, action string, reason string) {
+	fileActions.WithLabelValues(folder, action, reason).Inc()
+}
+
+// BytesFreed registers the number of bytes that were freed in a scan folder
+func BytesFreed(folder string, bytes int64) {
+	bytesFreed.WithLabelValues(folder).Add(float64(bytes))
+}
+
+// TraktRequest registers the duration and resulting status code of a request to the Trakt API.
+// A status code of 0 indicates that no response was received.
+func TraktRequest(endpoint string, statusCode int, duration time.Duration) {
+	status := strconv.Itoa(statusCode)
+	if statusCode == 0 {
+		status = "error"
+	}
+	traktRequestDuration.WithLabelValues(endpoint, status).Observe(duration.Seconds())
+}
+
+// TraktTokenExpiry registers when the Trakt access token expires
+func TraktTokenExpiry(expiresAt time.Time) {
+	traktTokenExpiry.Set(float64(expiresAt.Unix()))
+}
+
+// RunFinished registers the duration of a run and, if it was successful, its completion time
+func RunFinished(duration time.Duration, success bool) {
+	runDuration.Set(duration.Seconds())
+	if success {
+		lastSuccess.SetToCurrentTime()
+	}
+}
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	body, err := io.ReadAll(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestHandlerExposesRun(t *testing.T) {
+	// The metrics are global, so the run uses a folder of its own
+	const folder = "/metrics-test"
+	for i := 0; i < 3; i++ {
+		FileScanned(folder)
+	}
+	FileParsed(folder)
+	FileParsed(folder)
+	FileUnrecognized(folder)
+	Candidate(folder)
+	FileAction(folder, "deleted", "Episode was watched")
+	FileAction(folder, "skipped", "Episode is unwatched")
+	BytesFreed(folder, 1536)
+	TraktRequest("metrics-test/watched", http.StatusOK, 100*time.Millisecond)
+	TraktRequest("metrics-test/watched", 0, 2*time.Second)
+	RunFinished(30*time.Second, true)
+
+	body := scrape(t)
+	for _, want := range []string{
+		`series_cleanup_files_scanned_total{folder="/metrics-test"} 3`,
+		`series_cleanup_files_parsed_total{folder="/metrics-test"} 2`,
+		`series_cleanup_files_unrecognized_total{folder="/metrics-test"} 1`,
+		`series_cleanup_candidates_total{folder="/metrics-test"} 1`,
+		`series_cleanup_file_actions_total{action="deleted",folder="/metrics-test",reason="Episode was watched"} 1`,
+		`series_cleanup_file_actions_total{action="skipped",folder="/metrics-test",reason="Episode is unwatched"} 1`,
+		`series_cleanup_freed_bytes_total{folder="/metrics-test"} 1536`,
+		`series_cleanup_trakt_request_duration_seconds_bucket{endpoint="metrics-test/watched",status_code="200",le="0.1"} 1`,
+		`series_cleanup_trakt_request_duration_seconds_count{endpoint="metrics-test/watched",status_code="200"} 1`,
+		`series_cleanup_trakt_request_duration_seconds_bucket{endpoint="metrics-test/watched",status_code="error",le="1"} 0`,
+		`series_cleanup_trakt_request_duration_seconds_bucket{endpoint="metrics-test/watched",status_code="error",le="2.5"} 1`,
+		`series_cleanup_trakt_request_duration_seconds_sum{endpoint="metrics-test/watched",status_code="error"} 2`,
+		`series_cleanup_run_duration_seconds 30`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+	if strings.Contains(body, "series_cleanup_last_success_timestamp_seconds 0\n") {
+		t.Error("last_success_timestamp_seconds = 0, want the time of the successful run")
+	}
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ChatStyle is the payload format of a chat webhook
+type ChatStyle string
+
+const (
+	// ChatStyleDiscord sends the message as the content of a Discord webhook
+	ChatStyleDiscord ChatStyle = "discord"
+	// ChatStyleSlack sends the message as the text of a Slack incoming webhook
+	ChatStyleSlack ChatStyle = "slack"
+)
+
+// discordMessageLimit is the maximum length of a Discord message
+const discordMessageLimit = 2000
+
+// Chat sends notifications to a Discord or Slack style incoming webhook
+type Chat struct {
+	URL   string
+	Style ChatStyle
+}
+
+// Notify posts the run to the chat webhook
+func (chat *Chat) Notify(ctx context.Context, run Run) error {
+	var payload interface{}
+	switch chat.Style {
+	case ChatStyleSlack:
+		payload = map[string]string{"text": "*" + run.Title() + "*\n" + run.Message()}
+	default:
+		text := "**" + run.Title() + "**\n" + run.Message()
+		// The limit is in characters, and cutting a multi-byte character would make the text invalid UTF-8
+		if runes := []rune(text); len(runes) > discordMessageLimit {
+			text = string(runes[:discordMessageLimit-3]) + "..."
+		}
+		payload = map[string]string{"content": text}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return postRequest(ctx, http.MethodPost, chat.URL, "application/json", nil, body)
+}
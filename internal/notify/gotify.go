@@ -0,0 +1,39 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Gotify sends notifications to a Gotify server
+type Gotify struct {
+	URL      string
+	Token    string
+	Priority int
+}
+
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+// Notify sends the run as a message to the Gotify server
+func (gotify *Gotify) Notify(ctx context.Context, run Run) error {
+	body, err := json.Marshal(gotifyMessage{
+		Title:    run.Title(),
+		Message:  run.Message(),
+		Priority: gotify.Priority,
+	})
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"X-Gotify-Key": gotify.Token,
+	}
+	url := strings.TrimSuffix(gotify.URL, "/") + "/message"
+	return postRequest(ctx, http.MethodPost, url, "application/json", headers, body)
+}
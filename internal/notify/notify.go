@@ -0,0 +1,175 @@
+// Package notify implements the notifications that series-cleanup sends after a run
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Trigger determines when a notification is sent
+type Trigger string
+
+const (
+	// TriggerDeletion sends a notification when episodes were removed
+	TriggerDeletion Trigger = "deletion"
+	// TriggerError sends a notification when the run failed or files could not be processed
+	TriggerError Trigger = "error"
+	// TriggerAlways sends a notification after every run
+	TriggerAlways Trigger = "always"
+)
+
+// File represents a TV show file that was removed
+type File struct {
+	Path    string `json:"path"`
+	Show    string `json:"show"`
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	Size    int64  `json:"size"`
+}
+
+// Failure represents a file that could not be processed
+type Failure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Attachment is a file that is sent along with a notification
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+// Run is the summary of a run that notifications are based on
+type Run struct {
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	DryRun     bool      `json:"dryRun"`
+	Removed    []File    `json:"removed"`
+	Failures   []Failure `json:"failures"`
+	BytesFreed int64     `json:"bytesFreed"`
+	Error      string    `json:"error,omitempty"`
+	// Report is the run report, it is only sent by notifiers that support attachments
+	Report *Attachment `json:"report,omitempty"`
+}
+
+// HasErrors indicates if the run failed or any file could not be processed
+func (run Run) HasErrors() bool {
+	return run.Error != "" || len(run.Failures) > 0
+}
+
+// Title returns a short description of the run
+func (run Run) Title() string {
+	verb := "Removed"
+	if run.DryRun {
+		verb = "Would remove"
+	}
+
+	switch {
+	case run.Error != "":
+		return "series-cleanup run failed"
+	case len(run.Failures) > 0:
+		return fmt.Sprintf("series-cleanup: %v %d episode(s), %d failure(s)", strings.ToLower(verb), len(run.Removed), len(run.Failures))
+	default:
+		return fmt.Sprintf("series-cleanup: %v %d episode(s)", strings.ToLower(verb), len(run.Removed))
+	}
+}
+
+// Message returns a plain text description of the run
+func (run Run) Message() string {
+	var message strings.Builder
+
+	if run.Error != "" {
+		fmt.Fprintf(&message, "Error: %v\n", run.Error)
+	}
+
+	if len(run.Removed) > 0 {
+		verb := "Removed"
+		if run.DryRun {
+			verb = "Would remove"
+		}
+		fmt.Fprintf(&message, "%v %d episode(s), %v:\n", verb, len(run.Removed), helpers.FormatBytes(run.BytesFreed))
+		for _, file := range run.Removed {
+			fmt.Fprintf(&message, "- %v S%02dE%02d\n", file.Show, file.Season, file.Episode)
+		}
+	} else if run.Error == "" {
+		message.WriteString("No episodes were removed\n")
+	}
+
+	if len(run.Failures) > 0 {
+		fmt.Fprintf(&message, "Failed to process %d file(s):\n", len(run.Failures))
+		for _, failure := range run.Failures {
+			fmt.Fprintf(&message, "- %v: %v\n", failure.Path, failure.Error)
+		}
+	}
+
+	return strings.TrimSpace(message.String())
+}
+
+// Notifier sends a notification about a run
+type Notifier interface {
+	Notify(ctx context.Context, run Run) error
+}
+
+// Target combines a notifier with the triggers that determine when it is used
+type Target struct {
+	Name     string
+	Notifier Notifier
+	Triggers []Trigger
+}
+
+// ShouldNotify indicates if any of the triggers of the target apply to run
+func (target Target) ShouldNotify(run Run) bool {
+	for _, trigger := range target.Triggers {
+		switch trigger {
+		case TriggerAlways:
+			return true
+		case TriggerDeletion:
+			if len(run.Removed) > 0 {
+				return true
+			}
+		case TriggerError:
+			if run.HasErrors() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func postRequest(ctx context.Context, method string, url string, contentType string, headers map[string]string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("unexpected status code %v: %v", response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+
+	return nil
+}
@@ -0,0 +1,371 @@
+package notify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+var testRun = Run{
+	StartedAt:  time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+	FinishedAt: time.Date(2023, 5, 1, 12, 1, 0, 0, time.UTC),
+	Removed: []File{
+		{Path: "/media/Show/S01E01.mkv", Show: "Show", Season: 1, Episode: 1, Size: 1536},
+	},
+	Failures: []Failure{
+		{Path: "/media/Other/S01E01.mkv", Error: "permission denied"},
+	},
+	BytesFreed: 1536,
+}
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   string
+}
+
+func newRecordingServer(t *testing.T) (*httptest.Server, <-chan recordedRequest) {
+	t.Helper()
+	requests := make(chan recordedRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- recordedRequest{Method: r.Method, Path: r.URL.Path, Header: r.Header, Body: string(body)}
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func TestShouldNotify(t *testing.T) {
+	tests := []struct {
+		name     string
+		triggers []Trigger
+		run      Run
+		want     bool
+	}{
+		{"always", []Trigger{TriggerAlways}, Run{}, true},
+		{"deletion without removals", []Trigger{TriggerDeletion}, Run{}, false},
+		{"deletion with removals", []Trigger{TriggerDeletion}, Run{Removed: []File{{}}}, true},
+		{"error without errors", []Trigger{TriggerError}, Run{Removed: []File{{}}}, false},
+		{"error with failures", []Trigger{TriggerError}, Run{Failures: []Failure{{}}}, true},
+		{"error with run error", []Trigger{TriggerError}, Run{Error: "failed"}, true},
+		{"no triggers", nil, Run{Error: "failed"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			target := Target{Triggers: test.triggers}
+			if got := target.ShouldNotify(test.run); got != test.want {
+				t.Errorf("ShouldNotify() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	message := testRun.Message()
+	for _, want := range []string{"Removed 1 episode(s), 1.5 KiB", "- Show S01E01", "/media/Other/S01E01.mkv: permission denied"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Message() = %q, want it to contain %q", message, want)
+		}
+	}
+}
+
+func TestWebhookSendsJSON(t *testing.T) {
+	server, requests := newRecordingServer(t)
+
+	webhook, err := NewWebhook(server.URL+"/hook", "", map[string]string{"X-Token": "secret"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := webhook.Notify(context.Background(), testRun); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+	if request.Method != http.MethodPost || request.Path != "/hook" || request.Header.Get("X-Token") != "secret" {
+		t.Errorf("unexpected request: %+v", request)
+	}
+
+	var run Run
+	if err := json.Unmarshal([]byte(request.Body), &run); err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Removed) != 1 || run.Removed[0].Path != testRun.Removed[0].Path {
+		t.Errorf("unexpected body: %v", request.Body)
+	}
+}
+
+func TestWebhookRendersTemplate(t *testing.T) {
+	server, requests := newRecordingServer(t)
+
+	webhook, err := NewWebhook(server.URL, http.MethodPut, nil, `{"text": {{json .Title}}, "freed": "{{formatBytes .BytesFreed}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := webhook.Notify(context.Background(), testRun); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+	want := `{"text": "series-cleanup: removed 1 episode(s), 1 failure(s)", "freed": "1.5 KiB"}`
+	if request.Method != http.MethodPut || request.Body != want {
+		t.Errorf("got %v %v, want PUT %v", request.Method, request.Body, want)
+	}
+}
+
+func TestWebhookReturnsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	webhook, _ := NewWebhook(server.URL, "", nil, "")
+	if err := webhook.Notify(context.Background(), testRun); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Notify() error = %v, want status code error", err)
+	}
+}
+
+func TestNtfy(t *testing.T) {
+	server, requests := newRecordingServer(t)
+
+	ntfy := &Ntfy{URL: server.URL + "/", Topic: "media", Token: "tk", Priority: 4}
+	if err := ntfy.Notify(context.Background(), testRun); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+	if request.Path != "/media" {
+		t.Errorf("path = %v, want /media", request.Path)
+	}
+	if got := request.Header.Get("Authorization"); got != "Bearer tk" {
+		t.Errorf("Authorization = %v", got)
+	}
+	if got := request.Header.Get("Priority"); got != "4" {
+		t.Errorf("Priority = %v", got)
+	}
+	if got := request.Header.Get("Title"); got != testRun.Title() {
+		t.Errorf("Title = %v", got)
+	}
+	if request.Body != testRun.Message() {
+		t.Errorf("body = %v", request.Body)
+	}
+}
+
+func TestGotify(t *testing.T) {
+	server, requests := newRecordingServer(t)
+
+	gotify := &Gotify{URL: server.URL, Token: "app-token", Priority: 5}
+	if err := gotify.Notify(context.Background(), testRun); err != nil {
+		t.Fatal(err)
+	}
+
+	request := <-requests
+	if request.Path != "/message" || request.Header.Get("X-Gotify-Key") != "app-token" {
+		t.Errorf("unexpected request: %+v", request)
+	}
+
+	var message gotifyMessage
+	if err := json.Unmarshal([]byte(request.Body), &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.Title != testRun.Title() || message.Message != testRun.Message() || message.Priority != 5 {
+		t.Errorf("unexpected message: %+v", message)
+	}
+}
+
+func TestChat(t *testing.T) {
+	tests := []struct {
+		style ChatStyle
+		field string
+	}{
+		{ChatStyleDiscord, "content"},
+		{ChatStyleSlack, "text"},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.style), func(t *testing.T) {
+			server, requests := newRecordingServer(t)
+
+			chat := &Chat{URL: server.URL, Style: test.style}
+			if err := chat.Notify(context.Background(), testRun); err != nil {
+				t.Fatal(err)
+			}
+
+			var payload map[string]string
+			if err := json.Unmarshal([]byte((<-requests).Body), &payload); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(payload[test.field], "- Show S01E01") {
+				t.Errorf("payload = %v, want %v to contain the removed episode", payload, test.field)
+			}
+		})
+	}
+}
+
+func TestChatTruncatesDiscordMessage(t *testing.T) {
+	server, requests := newRecordingServer(t)
+
+	run := testRun
+	run.Removed = nil
+	for i := 0; i < 200; i++ {
+		run.Removed = append(run.Removed, File{Path: "/media/Pokémon/S01E01.mkv", Show: "Pokémon", Season: 1, Episode: i + 1})
+	}
+	chat := &Chat{URL: server.URL, Style: ChatStyleDiscord}
+	if err := chat.Notify(context.Background(), run); err != nil {
+		t.Fatal(err)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal([]byte((<-requests).Body), &payload); err != nil {
+		t.Fatal(err)
+	}
+	content := payload["content"]
+	if length := utf8.RuneCountInString(content); length != discordMessageLimit || !utf8.ValidString(content) || !strings.HasSuffix(content, "...") {
+		t.Errorf("content has %d characters, want it to be truncated to %d characters of valid UTF-8", length, discordMessageLimit)
+	}
+}
+
+// serveSMTP accepts a single SMTP session on listener and returns the received message
+func serveSMTP(t *testing.T, listener net.Listener) <-chan string {
+	t.Helper()
+	messages := make(chan string, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			_, _ = conn.Write([]byte(line + "\r\n"))
+		}
+
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				reply("354 end data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				messages <- data.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return messages
+}
+
+func TestSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	messages := serveSMTP(t, listener)
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	mail := &SMTP{
+		Host: host,
+		Port: portNumber,
+		From: "series-cleanup@example.com",
+		To:   []string{"admin@example.com"},
+	}
+	if err := mail.Notify(context.Background(), testRun); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case message := <-messages:
+		for _, want := range []string{"Subject: " + testRun.Title(), "To: admin@example.com", "- Show S01E01"} {
+			if !strings.Contains(message, want) {
+				t.Errorf("message = %q, want it to contain %q", message, want)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+func TestSMTPMessageAttachesReport(t *testing.T) {
+	run := testRun
+	run.Report = &Attachment{
+		Filename:    "report.html",
+		ContentType: "text/html; charset=utf-8",
+		Content:     "<h1>" + strings.Repeat("report ", 50) + "</h1>",
+	}
+
+	message, err := buildMessage("series-cleanup@example.com", []string{"admin@example.com"}, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %v, %v", mediaType, err)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	if _, err := reader.NextPart(); err != nil {
+		t.Fatal(err)
+	}
+	attachment, err := reader.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attachment.FileName() != "report.html" {
+		t.Errorf("attachment filename = %v, want report.html", attachment.FileName())
+	}
+
+	content, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != run.Report.Content {
+		t.Errorf("attachment content = %q, want %q", content, run.Report.Content)
+	}
+}
@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Ntfy sends notifications to a topic on a ntfy server
+type Ntfy struct {
+	URL      string
+	Topic    string
+	Token    string
+	Priority int
+}
+
+// Notify publishes the run to the ntfy topic
+func (ntfy *Ntfy) Notify(ctx context.Context, run Run) error {
+	headers := map[string]string{
+		"Title": run.Title(),
+	}
+	if ntfy.Token != "" {
+		headers["Authorization"] = "Bearer " + ntfy.Token
+	}
+	if ntfy.Priority != 0 {
+		headers["Priority"] = strconv.Itoa(ntfy.Priority)
+	}
+	if run.HasErrors() {
+		headers["Tags"] = "warning"
+	}
+
+	url := strings.TrimSuffix(ntfy.URL, "/") + "/" + ntfy.Topic
+	return postRequest(ctx, http.MethodPost, url, "text/plain; charset=utf-8", headers, []byte(run.Message()))
+}
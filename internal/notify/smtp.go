@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP sends notifications by email
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Notify emails the run to all recipients
+func (mail *SMTP) Notify(ctx context.Context, run Run) error {
+	address := net.JoinHostPort(mail.Host, strconv.Itoa(mail.Port))
+
+	var auth smtp.Auth
+	if mail.Username != "" {
+		auth = smtp.PlainAuth("", mail.Username, mail.Password, mail.Host)
+	}
+
+	message, err := buildMessage(mail.From, mail.To, run)
+	if err != nil {
+		return err
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- smtp.SendMail(address, auth, mail.From, mail.To, message)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-errs:
+		return err
+	}
+}
+
+// buildMessage returns the email for run, with the report attached when it is available
+func buildMessage(from string, to []string, run Run) ([]byte, error) {
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %v\r\n", from)
+	fmt.Fprintf(&message, "To: %v\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %v\r\n", mime.QEncoding.Encode("utf-8", run.Title()))
+	fmt.Fprintf(&message, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+
+	body := strings.ReplaceAll(run.Message(), "\n", "\r\n") + "\r\n"
+	if run.Report == nil {
+		message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		message.WriteString(body)
+		return message.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&message)
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%v\r\n\r\n", parts.Boundary())
+
+	text, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := text.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+
+	attachment, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {run.Report.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": run.Report.Filename})},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoder := base64.NewEncoder(base64.StdEncoding, &lineWriter{writer: attachment})
+	if _, err := encoder.Write([]byte(run.Report.Content)); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return message.Bytes(), nil
+}
+
+// maxLineLength is the line length that base64 encoded attachments are wrapped at, as required by RFC 2045
+const maxLineLength = 76
+
+// lineWriter wraps everything written to it at maxLineLength characters
+type lineWriter struct {
+	writer io.Writer
+	length int
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := maxLineLength - w.length
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := w.writer.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		w.length += n
+		p = p[n:]
+
+		if w.length == maxLineLength {
+			if _, err := w.writer.Write([]byte("\r\n")); err != nil {
+				return written, err
+			}
+			w.length = 0
+		}
+	}
+	return written, nil
+}
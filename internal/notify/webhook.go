@@ -0,0 +1,73 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"text/template"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+// Webhook sends the run as JSON, or rendered through a template, to a URL
+type Webhook struct {
+	URL     string
+	Method  string
+	Headers map[string]string
+	body    *template.Template
+}
+
+// NewWebhook creates a new Webhook instance.
+// When bodyTemplate is empty, the run is sent as JSON. Otherwise the body is rendered
+// from bodyTemplate, which is executed with the Run and has access to the json and formatBytes functions.
+func NewWebhook(url string, method string, headers map[string]string, bodyTemplate string) (*Webhook, error) {
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	webhook := &Webhook{
+		URL:     url,
+		Method:  method,
+		Headers: headers,
+	}
+
+	if bodyTemplate != "" {
+		tmpl, err := ParseBodyTemplate(bodyTemplate)
+		if err != nil {
+			return nil, err
+		}
+		webhook.body = tmpl
+	}
+
+	return webhook, nil
+}
+
+// ParseBodyTemplate parses the template of a webhook body, which has access to the json and
+// formatBytes functions
+func ParseBodyTemplate(bodyTemplate string) (*template.Template, error) {
+	return template.New("body").Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+		"formatBytes": helpers.FormatBytes,
+	}).Parse(bodyTemplate)
+}
+
+// Notify sends the run to the webhook
+func (webhook *Webhook) Notify(ctx context.Context, run Run) error {
+	if webhook.body == nil {
+		body, err := json.Marshal(run)
+		if err != nil {
+			return err
+		}
+		return postRequest(ctx, webhook.Method, webhook.URL, "application/json", webhook.Headers, body)
+	}
+
+	var body bytes.Buffer
+	if err := webhook.body.Execute(&body, run); err != nil {
+		return err
+	}
+	return postRequest(ctx, webhook.Method, webhook.URL, "application/json", webhook.Headers, body.Bytes())
+}
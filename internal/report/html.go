@@ -0,0 +1,94 @@
+package report
+
+import (
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"formatBytes": helpers.FormatBytes,
+	"formatTime": func(t time.Time) string {
+		return t.Local().Format(time.RFC3339)
+	},
+	"episodeCode": episodeCode,
+	"actionLabel": actionLabel,
+	"failures": func(title string, failures []Failure) failureSection {
+		return failureSection{Title: title, Failures: failures}
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>series-cleanup report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #f0f0f0; }
+td.number { text-align: right; }
+td.path { font-family: monospace; font-size: 0.9em; }
+tr.deleted td, tr.would_delete td { background: #fdecea; }
+tr.kept td { background: #eaf4fd; }
+tr.pending_approval td { background: #fff8e1; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>series-cleanup report{{if .DryRun}} (dry run){{end}}</h1>
+<p>Started {{formatTime .StartedAt}}, finished {{formatTime .FinishedAt}}.</p>
+{{- if .Error}}
+<p class="error"><strong>Error:</strong> {{.Error}}</p>
+{{- end}}
+{{- with .Totals}}
+<h2>Totals</h2>
+<table>
+<tr><th>Action</th><th>Files</th><th>Size</th></tr>
+<tr><td>Deleted</td><td class="number">{{.Deleted}}</td><td class="number">{{formatBytes .BytesFreed}}</td></tr>
+{{- if $.DryRun}}
+<tr><td>Would delete</td><td class="number">{{.WouldDelete}}</td><td class="number">{{formatBytes .BytesToFree}}</td></tr>
+{{- end}}
+<tr><td>Kept</td><td class="number">{{.Kept}}</td><td></td></tr>
+<tr><td>Skipped</td><td class="number">{{.Skipped}}</td><td></td></tr>
+{{- if .Pending}}
+<tr><td>Pending approval</td><td class="number">{{.Pending}}</td><td></td></tr>
+{{- end}}
+<tr><td>Unrecognized</td><td class="number">{{.Unrecognized}}</td><td></td></tr>
+<tr><td>Failed</td><td class="number">{{.Failed}}</td><td></td></tr>
+</table>
+{{- end}}
+{{- range .Shows}}
+<h2>{{.Name}}</h2>
+<table>
+<tr><th>Episode</th><th>Action</th><th>Size</th><th>Reason</th><th>File</th></tr>
+{{- range .Files}}
+<tr class="{{.Action}}"><td>{{episodeCode .}}</td><td>{{actionLabel .Action}}</td><td class="number">{{formatBytes .Size}}</td><td>{{.Reason}}</td><td class="path">{{.Path}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- template "failures" (failures "Unrecognized files" .Unrecognized)}}
+{{- template "failures" (failures "Failed files" .Failed)}}
+</body>
+</html>
+{{define "failures"}}{{if .Failures}}
+<h2>{{.Title}}</h2>
+<table>
+<tr><th>File</th><th>Error</th></tr>
+{{- range .Failures}}
+<tr><td class="path">{{.Path}}</td><td class="error">{{.Error}}</td></tr>
+{{- end}}
+</table>
+{{- end}}{{end}}
+`))
+
+// failureSection is the input of the failures template
+type failureSection struct {
+	Title    string
+	Failures []Failure
+}
+
+func renderHTML(w io.Writer, report Report) error {
+	return htmlTemplate.Execute(w, report)
+}
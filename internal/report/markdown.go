@@ -0,0 +1,71 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "\n", " ", "\r", "")
+
+func renderMarkdown(w io.Writer, report Report) error {
+	var b strings.Builder
+
+	title := "series-cleanup report"
+	if report.DryRun {
+		title += " (dry run)"
+	}
+	fmt.Fprintf(&b, "# %v\n\n", title)
+	fmt.Fprintf(&b, "Started %v, finished %v.\n\n",
+		report.StartedAt.Local().Format(time.RFC3339), report.FinishedAt.Local().Format(time.RFC3339),
+	)
+	if report.Error != "" {
+		fmt.Fprintf(&b, "**Error:** %v\n\n", markdownEscaper.Replace(report.Error))
+	}
+
+	totals := report.Totals()
+	b.WriteString("## Totals\n\n")
+	b.WriteString("| Action | Files | Size |\n|---|---:|---:|\n")
+	fmt.Fprintf(&b, "| Deleted | %d | %v |\n", totals.Deleted, helpers.FormatBytes(totals.BytesFreed))
+	if report.DryRun {
+		fmt.Fprintf(&b, "| Would delete | %d | %v |\n", totals.WouldDelete, helpers.FormatBytes(totals.BytesToFree))
+	}
+	fmt.Fprintf(&b, "| Kept | %d | |\n", totals.Kept)
+	fmt.Fprintf(&b, "| Skipped | %d | |\n", totals.Skipped)
+	if totals.Pending > 0 {
+		fmt.Fprintf(&b, "| Pending approval | %d | |\n", totals.Pending)
+	}
+	fmt.Fprintf(&b, "| Unrecognized | %d | |\n", totals.Unrecognized)
+	fmt.Fprintf(&b, "| Failed | %d | |\n", totals.Failed)
+
+	for _, show := range report.Shows() {
+		fmt.Fprintf(&b, "\n## %v\n\n", markdownEscaper.Replace(show.Name))
+		b.WriteString("| Episode | Action | Size | Reason | File |\n|---|---|---:|---|---|\n")
+		for _, file := range show.Files {
+			fmt.Fprintf(&b, "| %v | %v | %v | %v | `%v` |\n",
+				episodeCode(file), actionLabel(file.Action), helpers.FormatBytes(file.Size),
+				markdownEscaper.Replace(file.Reason), markdownEscaper.Replace(file.Path),
+			)
+		}
+	}
+
+	writeMarkdownFailures(&b, "Unrecognized files", report.Unrecognized)
+	writeMarkdownFailures(&b, "Failed files", report.Failed)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownFailures(b *strings.Builder, title string, failures []Failure) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n## %v\n\n", title)
+	b.WriteString("| File | Error |\n|---|---|\n")
+	for _, failure := range failures {
+		fmt.Fprintf(b, "| `%v` | %v |\n", markdownEscaper.Replace(failure.Path), markdownEscaper.Replace(failure.Error))
+	}
+}
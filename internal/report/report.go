@@ -0,0 +1,219 @@
+// Package report implements the human-readable reports of a series-cleanup run
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Format is the output format of a report
+type Format string
+
+const (
+	// FormatText renders the report as plain text tables
+	FormatText Format = "text"
+	// FormatMarkdown renders the report as Markdown
+	FormatMarkdown Format = "markdown"
+	// FormatHTML renders the report as a self-contained HTML page
+	FormatHTML Format = "html"
+)
+
+// ParseFormat returns the Format with the given name
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case FormatText, "txt":
+		return FormatText, nil
+	case FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	case FormatHTML:
+		return FormatHTML, nil
+	default:
+		return "", fmt.Errorf("unknown report format: %v", name)
+	}
+}
+
+// FormatFromPath returns the Format matching the extension of path, defaulting to FormatText
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return FormatHTML
+	case ".md", ".markdown":
+		return FormatMarkdown
+	default:
+		return FormatText
+	}
+}
+
+// ContentType returns the MIME type of reports in the format
+func (format Format) ContentType() string {
+	switch format {
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Extension returns the file extension of reports in the format
+func (format Format) Extension() string {
+	switch format {
+	case FormatHTML:
+		return ".html"
+	case FormatMarkdown:
+		return ".md"
+	default:
+		return ".txt"
+	}
+}
+
+// File is a TV show file that was processed during the run
+type File struct {
+	Path    string `json:"path"`
+	Show    string `json:"show"`
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	Size    int64  `json:"size"`
+	Action  string `json:"action"`
+	Reason  string `json:"reason"`
+}
+
+// Failure is a file that could not be recognized or processed
+type Failure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// Report describes the outcome of a run
+type Report struct {
+	StartedAt    time.Time
+	FinishedAt   time.Time
+	DryRun       bool
+	Files        []File
+	Unrecognized []Failure
+	Failed       []Failure
+	Error        string
+}
+
+// Show groups the processed files of a single show
+type Show struct {
+	Name  string
+	Files []File
+}
+
+// Totals are the number of files per action and the number of bytes freed
+type Totals struct {
+	Deleted      int
+	WouldDelete  int
+	Kept         int
+	Skipped      int
+	Pending      int
+	Unrecognized int
+	Failed       int
+	BytesFreed   int64
+	// BytesToFree is the size of the files that would be removed in a dry run
+	BytesToFree int64
+}
+
+// Shows returns the processed files grouped by show, sorted by show name and episode
+func (report Report) Shows() []Show {
+	byName := map[string]*Show{}
+	for _, file := range report.Files {
+		show, ok := byName[file.Show]
+		if !ok {
+			show = &Show{Name: file.Show}
+			byName[file.Show] = show
+		}
+		show.Files = append(show.Files, file)
+	}
+
+	shows := make([]Show, 0, len(byName))
+	for _, show := range byName {
+		sort.Slice(show.Files, func(i, j int) bool {
+			a, b := show.Files[i], show.Files[j]
+			if a.Season != b.Season {
+				return a.Season < b.Season
+			}
+			if a.Episode != b.Episode {
+				return a.Episode < b.Episode
+			}
+			return a.Path < b.Path
+		})
+		shows = append(shows, *show)
+	}
+	sort.Slice(shows, func(i, j int) bool {
+		return strings.ToLower(shows[i].Name) < strings.ToLower(shows[j].Name)
+	})
+	return shows
+}
+
+// Totals returns the totals of the run
+func (report Report) Totals() Totals {
+	totals := Totals{
+		Unrecognized: len(report.Unrecognized),
+		Failed:       len(report.Failed),
+	}
+	for _, file := range report.Files {
+		switch file.Action {
+		case "deleted":
+			totals.Deleted++
+			totals.BytesFreed += file.Size
+		case "would_delete":
+			totals.WouldDelete++
+			totals.BytesToFree += file.Size
+		case "kept":
+			totals.Kept++
+		case "skipped":
+			totals.Skipped++
+		case "pending_approval":
+			totals.Pending++
+		}
+	}
+	return totals
+}
+
+// Render writes the report to w in the given format
+func (report Report) Render(w io.Writer, format Format) error {
+	switch format {
+	case FormatHTML:
+		return renderHTML(w, report)
+	case FormatMarkdown:
+		return renderMarkdown(w, report)
+	default:
+		return renderText(w, report)
+	}
+}
+
+// String renders the report in the given format
+func (report Report) String(format Format) (string, error) {
+	var builder strings.Builder
+	err := report.Render(&builder, format)
+	return builder.String(), err
+}
+
+func episodeCode(file File) string {
+	return fmt.Sprintf("S%02dE%02d", file.Season, file.Episode)
+}
+
+// actionLabel returns a human-readable description of an action
+func actionLabel(action string) string {
+	switch action {
+	case "deleted":
+		return "Deleted"
+	case "would_delete":
+		return "Would delete"
+	case "kept":
+		return "Kept"
+	case "skipped":
+		return "Skipped"
+	case "pending_approval":
+		return "Pending approval"
+	default:
+		return action
+	}
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testReport = Report{
+	StartedAt:  time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+	FinishedAt: time.Date(2023, 5, 1, 12, 1, 0, 0, time.UTC),
+	DryRun:     true,
+	Files: []File{
+		{Path: "/media/b/S01E02.mkv", Show: "Beta", Season: 1, Episode: 2, Size: 2048, Action: "would_delete", Reason: "Dry run"},
+		{Path: "/media/a/S02E01.mkv", Show: "alpha", Season: 2, Episode: 1, Size: 100, Action: "kept", Reason: "Episode was watched too recently"},
+		{Path: "/media/a/S01E01.mkv", Show: "alpha", Season: 1, Episode: 1, Size: 100, Action: "skipped", Reason: "Show <unwatched> | gone"},
+	},
+	Unrecognized: []Failure{{Path: "/media/c/extras.mkv", Error: "could not determine season"}},
+}
+
+func TestShowsAreGroupedAndSorted(t *testing.T) {
+	shows := testReport.Shows()
+	if len(shows) != 2 || shows[0].Name != "alpha" || shows[1].Name != "Beta" {
+		t.Fatalf("Shows() = %+v, want alpha and Beta", shows)
+	}
+	if shows[0].Files[0].Season != 1 || shows[0].Files[1].Season != 2 {
+		t.Errorf("files of alpha are not sorted by episode: %+v", shows[0].Files)
+	}
+}
+
+func TestTotals(t *testing.T) {
+	totals := testReport.Totals()
+	want := Totals{WouldDelete: 1, Kept: 1, Skipped: 1, Unrecognized: 1, BytesToFree: 2048}
+	if totals != want {
+		t.Errorf("Totals() = %+v, want %+v", totals, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   []string
+	}{
+		{FormatText, []string{"(dry run)", "S01E02  Would delete  2.0 KiB", "Unrecognized files", "Would delete:  1"}},
+		{FormatMarkdown, []string{"# series-cleanup report (dry run)", "## Beta", "| S01E02 | Would delete | 2.0 KiB | Dry run | `/media/b/S01E02.mkv` |", `Show <unwatched> \| gone`}},
+		{FormatHTML, []string{"<h2>Beta</h2>", `<tr class="would_delete">`, "Show &lt;unwatched&gt; | gone", "<h2>Unrecognized files</h2>"}},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.format), func(t *testing.T) {
+			output, err := testReport.String(test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(output, want) {
+					t.Errorf("output does not contain %q:\n%v", want, output)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	for path, want := range map[string]Format{
+		"/reports/last.html": FormatHTML,
+		"/reports/last.MD":   FormatMarkdown,
+		"/reports/last.txt":  FormatText,
+		"/reports/last":      FormatText,
+	} {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%v) = %v, want %v", path, got, want)
+		}
+	}
+}
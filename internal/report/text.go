@@ -0,0 +1,63 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+func renderText(w io.Writer, report Report) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	title := "series-cleanup report"
+	if report.DryRun {
+		title += " (dry run)"
+	}
+	fmt.Fprintf(writer, "%v\n", title)
+	fmt.Fprintf(writer, "Started:\t%v\n", report.StartedAt.Local().Format(time.RFC3339))
+	fmt.Fprintf(writer, "Finished:\t%v\n", report.FinishedAt.Local().Format(time.RFC3339))
+	if report.Error != "" {
+		fmt.Fprintf(writer, "Error:\t%v\n", report.Error)
+	}
+
+	for _, show := range report.Shows() {
+		fmt.Fprintf(writer, "\n%v\n", show.Name)
+		for _, file := range show.Files {
+			fmt.Fprintf(writer, "  %v\t%v\t%v\t%v\t%v\n",
+				episodeCode(file), actionLabel(file.Action), helpers.FormatBytes(file.Size), file.Reason, file.Path,
+			)
+		}
+	}
+
+	writeTextFailures(writer, "Unrecognized files", report.Unrecognized)
+	writeTextFailures(writer, "Failed files", report.Failed)
+
+	totals := report.Totals()
+	fmt.Fprintf(writer, "\nTotals\n")
+	fmt.Fprintf(writer, "  Deleted:\t%d\t%v\n", totals.Deleted, helpers.FormatBytes(totals.BytesFreed))
+	if report.DryRun {
+		fmt.Fprintf(writer, "  Would delete:\t%d\t%v\n", totals.WouldDelete, helpers.FormatBytes(totals.BytesToFree))
+	}
+	fmt.Fprintf(writer, "  Kept:\t%d\t\n", totals.Kept)
+	fmt.Fprintf(writer, "  Skipped:\t%d\t\n", totals.Skipped)
+	if totals.Pending > 0 {
+		fmt.Fprintf(writer, "  Pending approval:\t%d\t\n", totals.Pending)
+	}
+	fmt.Fprintf(writer, "  Unrecognized:\t%d\t\n", totals.Unrecognized)
+	fmt.Fprintf(writer, "  Failed:\t%d\t\n", totals.Failed)
+
+	return writer.Flush()
+}
+
+func writeTextFailures(w io.Writer, title string, failures []Failure) {
+	if len(failures) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%v\n", title)
+	for _, failure := range failures {
+		fmt.Fprintf(w, "  %v\t%v\n", failure.Path, failure.Error)
+	}
+}
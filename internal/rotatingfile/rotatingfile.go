@@ -0,0 +1,126 @@
+// Package rotatingfile implements an append-only file that is rotated when it exceeds a maximum size
+package rotatingfile
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// File is an append-only file that is rotated when writing to it would exceed its maximum size.
+// Rotated files are renamed to <path>.1, <path>.2, ... with <path>.1 being the most recent.
+// It is safe for concurrent use.
+type File struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+// Open opens the file at path for appending, creating it if it does not exist yet.
+// When maxSize is 0 the file is never rotated. At most maxBackups rotated files are kept.
+func Open(path string, maxSize int64, maxBackups int) (*File, error) {
+	rotatingFile := &File{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+
+	if err := rotatingFile.open(); err != nil {
+		return nil, err
+	}
+	return rotatingFile, nil
+}
+
+func (rotatingFile *File) open() error {
+	file, err := os.OpenFile(rotatingFile.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	rotatingFile.file = file
+	rotatingFile.size = info.Size()
+	return nil
+}
+
+// Write appends p to the file, rotating it first if p would not fit anymore.
+// p is never split across files.
+func (rotatingFile *File) Write(p []byte) (int, error) {
+	rotatingFile.mutex.Lock()
+	defer rotatingFile.mutex.Unlock()
+
+	if rotatingFile.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if rotatingFile.maxSize > 0 && rotatingFile.size > 0 && rotatingFile.size+int64(len(p)) > rotatingFile.maxSize {
+		if err := rotatingFile.rotate(); err != nil {
+			return 0, fmt.Errorf("could not rotate %v: %w", rotatingFile.path, err)
+		}
+	}
+
+	n, err := rotatingFile.file.Write(p)
+	rotatingFile.size += int64(n)
+	return n, err
+}
+
+func (rotatingFile *File) rotate() error {
+	if err := rotatingFile.file.Close(); err != nil {
+		return err
+	}
+	rotatingFile.file = nil
+
+	if rotatingFile.maxBackups > 0 {
+		for i := rotatingFile.maxBackups - 1; i > 0; i-- {
+			err := os.Rename(BackupPath(rotatingFile.path, i), BackupPath(rotatingFile.path, i+1))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(rotatingFile.path, BackupPath(rotatingFile.path, 1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(rotatingFile.path); err != nil {
+		return err
+	}
+
+	return rotatingFile.open()
+}
+
+// Sync commits the contents of the file to disk
+func (rotatingFile *File) Sync() error {
+	rotatingFile.mutex.Lock()
+	defer rotatingFile.mutex.Unlock()
+
+	if rotatingFile.file == nil {
+		return os.ErrClosed
+	}
+	return rotatingFile.file.Sync()
+}
+
+// Close closes the file
+func (rotatingFile *File) Close() error {
+	rotatingFile.mutex.Lock()
+	defer rotatingFile.mutex.Unlock()
+
+	if rotatingFile.file == nil {
+		return nil
+	}
+	err := rotatingFile.file.Close()
+	rotatingFile.file = nil
+	return err
+}
+
+// BackupPath returns the path of the n-th most recent rotated file of path
+func BackupPath(path string, n int) string {
+	return fmt.Sprintf("%v.%d", path, n)
+}
@@ -0,0 +1,65 @@
+package rotatingfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	file, err := Open(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		path:                "fourth\n",
+		BackupPath(path, 1): "third\n",
+		BackupPath(path, 2): "second\n",
+	}
+	for path, content := range want {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%v = %q, want %q", filepath.Base(path), got, content)
+		}
+	}
+
+	if _, err := os.Stat(BackupPath(path, 3)); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, stat %v: %v", BackupPath(path, 3), err)
+	}
+}
+
+func TestReopenAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	for _, line := range []string{"a\n", "b\n"} {
+		file, err := Open(path, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "a\nb\n" {
+		t.Errorf("content = %q, want %q", got, "a\nb\n")
+	}
+}
@@ -0,0 +1,270 @@
+// Package scrobble implements the webhooks through which media servers report watched episodes
+package scrobble
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRequestSize is the largest request body accepted by the webhooks.
+// Plex sends a thumbnail along with the payload, so this is larger than the payload itself.
+const maxRequestSize = 16 * 1024 * 1024
+
+// maxMemory is how much of a multipart request is kept in memory while it is parsed
+const maxMemory = 1024 * 1024
+
+// Source is the kind of client that reported an episode as watched
+type Source string
+
+const (
+	// SourcePlex is a Plex Media Server webhook
+	SourcePlex Source = "plex"
+	// SourceJellyfin is a webhook sent by the Jellyfin webhook plugin
+	SourceJellyfin Source = "jellyfin"
+	// SourceGeneric is a webhook with the generic JSON payload
+	SourceGeneric Source = "generic"
+)
+
+// Event is an episode that was reported as watched
+type Event struct {
+	Source  Source `json:"source"`
+	Show    string `json:"show"`
+	Season  int    `json:"season"`
+	Episode int    `json:"episode"`
+	// Path is the path of the watched file, when it was reported
+	Path      string    `json:"path,omitempty"`
+	WatchedAt time.Time `json:"watchedAt"`
+	// User is the user of the media server who watched the episode, when it was reported
+	User string `json:"user,omitempty"`
+}
+
+// ReceiveFunc handles an episode that was reported as watched
+type ReceiveFunc func(ctx context.Context, event Event) error
+
+// Handler serves the webhooks, one per source below its root
+type Handler struct {
+	token   string
+	receive ReceiveFunc
+	mux     *http.ServeMux
+}
+
+// NewHandler creates a new Handler instance. When token is not empty, every request must
+// include it in the token query parameter or as a bearer token.
+func NewHandler(token string, receive ReceiveFunc) *Handler {
+	handler := &Handler{
+		token:   token,
+		receive: receive,
+		mux:     http.NewServeMux(),
+	}
+
+	handler.mux.HandleFunc("/plex", handler.handle(SourcePlex, parsePlex))
+	handler.mux.HandleFunc("/jellyfin", handler.handle(SourceJellyfin, parseJellyfin))
+	handler.mux.HandleFunc("/generic", handler.handle(SourceGeneric, parseGeneric))
+
+	return handler
+}
+
+// ServeHTTP implements http.Handler
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler.mux.ServeHTTP(w, r)
+}
+
+// parseFunc parses a webhook request. It returns nil when the request does not report a watched episode.
+type parseFunc func(r *http.Request) (*Event, error)
+
+func (handler *Handler) handle(source Source, parse parseFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+
+		if !handler.authorized(r) {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+		event, err := parse(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if event == nil {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+			return
+		}
+
+		event.Source = source
+		if event.WatchedAt.IsZero() {
+			event.WatchedAt = time.Now()
+		}
+		if err := handler.receive(r.Context(), *event); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]string{"status": "accepted"})
+	}
+}
+
+func (handler *Handler) authorized(r *http.Request) bool {
+	// Without a token nobody could be trusted to report watched episodes
+	if handler.token == "" {
+		return false
+	}
+
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(handler.token)) == 1
+}
+
+// plexPayload is the part of a Plex webhook payload that describes a watched episode
+type plexPayload struct {
+	Event   string `json:"event"`
+	Account struct {
+		Title string `json:"title"`
+	} `json:"Account"`
+	Metadata struct {
+		Type             string `json:"type"`
+		GrandparentTitle string `json:"grandparentTitle"`
+		ParentIndex      int    `json:"parentIndex"`
+		Index            int    `json:"index"`
+		LastViewedAt     int64  `json:"lastViewedAt"`
+	} `json:"Metadata"`
+}
+
+// parsePlex parses a Plex webhook, which is a multipart form with the JSON payload in the payload field
+func parsePlex(r *http.Request) (*Event, error) {
+	if err := r.ParseMultipartForm(maxMemory); err != nil {
+		return nil, fmt.Errorf("could not parse Plex webhook: %w", err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	var payload plexPayload
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), &payload); err != nil {
+		return nil, fmt.Errorf("could not parse Plex webhook payload: %w", err)
+	}
+
+	if payload.Event != "media.scrobble" || payload.Metadata.Type != "episode" {
+		return nil, nil
+	}
+
+	event := &Event{
+		Show:    payload.Metadata.GrandparentTitle,
+		Season:  payload.Metadata.ParentIndex,
+		Episode: payload.Metadata.Index,
+		User:    payload.Account.Title,
+	}
+	if payload.Metadata.LastViewedAt > 0 {
+		event.WatchedAt = time.Unix(payload.Metadata.LastViewedAt, 0)
+	}
+	return event, event.validate()
+}
+
+// jellyfinPayload is the part of a Jellyfin webhook plugin payload that describes a watched episode
+type jellyfinPayload struct {
+	NotificationType     string `json:"NotificationType"`
+	ItemType             string `json:"ItemType"`
+	SeriesName           string `json:"SeriesName"`
+	SeasonNumber         int    `json:"SeasonNumber"`
+	EpisodeNumber        int    `json:"EpisodeNumber"`
+	PlayedToCompletion   bool   `json:"PlayedToCompletion"`
+	Played               bool   `json:"Played"`
+	UtcTimestamp         string `json:"UtcTimestamp"`
+	NotificationUsername string `json:"NotificationUsername"`
+}
+
+// parseJellyfin parses a webhook of the Jellyfin webhook plugin. An episode is watched when playback
+// stopped after it was played to completion, or when it was marked as played.
+func parseJellyfin(r *http.Request) (*Event, error) {
+	var payload jellyfinPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("could not parse Jellyfin webhook: %w", err)
+	}
+
+	if payload.ItemType != "Episode" {
+		return nil, nil
+	}
+	switch payload.NotificationType {
+	case "PlaybackStop":
+		if !payload.PlayedToCompletion {
+			return nil, nil
+		}
+	case "UserDataSaved":
+		if !payload.Played {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	event := &Event{
+		Show:    payload.SeriesName,
+		Season:  payload.SeasonNumber,
+		Episode: payload.EpisodeNumber,
+		User:    payload.NotificationUsername,
+	}
+	// The timestamp is optional, as the payload is defined by a template in the plugin
+	if watchedAt, err := time.Parse(time.RFC3339Nano, payload.UtcTimestamp); err == nil {
+		event.WatchedAt = watchedAt
+	}
+	return event, event.validate()
+}
+
+// genericPayload is the payload of the generic webhook
+type genericPayload struct {
+	Show      string    `json:"show"`
+	Season    int       `json:"season"`
+	Episode   int       `json:"episode"`
+	Path      string    `json:"path"`
+	WatchedAt time.Time `json:"watchedAt"`
+	User      string    `json:"user"`
+}
+
+// parseGeneric parses a webhook with the generic JSON payload
+func parseGeneric(r *http.Request) (*Event, error) {
+	var payload genericPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("could not parse webhook: %w", err)
+	}
+
+	event := &Event{
+		Show:      payload.Show,
+		Season:    payload.Season,
+		Episode:   payload.Episode,
+		Path:      payload.Path,
+		WatchedAt: payload.WatchedAt,
+		User:      payload.User,
+	}
+	if event.Path != "" {
+		return event, nil
+	}
+	return event, event.validate()
+}
+
+// validate checks that the episode is identified
+func (event *Event) validate() error {
+	if event.Show == "" || event.Episode <= 0 {
+		return errors.New("the show and episode of the watched episode are required")
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]string{"error": err.Error()})
+}
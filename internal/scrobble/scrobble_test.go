@@ -0,0 +1,185 @@
+package scrobble
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHandler(token string) (*Handler, *[]Event) {
+	var events []Event
+	handler := NewHandler(token, func(_ context.Context, event Event) error {
+		events = append(events, event)
+		return nil
+	})
+	return handler, &events
+}
+
+func plexRequest(t *testing.T, payload string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("payload", payload); err != nil {
+		t.Fatal(err)
+	}
+	thumb, err := writer.CreateFormFile("thumb", "thumb.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = thumb.Write([]byte("image"))
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/plex?token=secret", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestPlexScrobble(t *testing.T) {
+	handler, events := newTestHandler("secret")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, plexRequest(t, `{
+		"event": "media.scrobble",
+		"Account": {"title": "alice"},
+		"Metadata": {"type": "episode", "grandparentTitle": "The Office", "parentIndex": 2, "index": 5, "lastViewedAt": 1682942400}
+	}`))
+
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusAccepted, recorder.Body)
+	}
+	want := Event{Source: SourcePlex, Show: "The Office", Season: 2, Episode: 5, WatchedAt: time.Unix(1682942400, 0), User: "alice"}
+	if len(*events) != 1 || (*events)[0] != want {
+		t.Errorf("events = %+v, want %+v", *events, want)
+	}
+}
+
+func TestPlexIgnoresOtherEvents(t *testing.T) {
+	handler, events := newTestHandler("secret")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, plexRequest(t, `{"event": "media.play", "Metadata": {"type": "episode", "grandparentTitle": "The Office", "index": 5}}`))
+
+	if recorder.Code != http.StatusOK || len(*events) != 0 {
+		t.Errorf("status = %d, events = %+v, want the event to be ignored", recorder.Code, *events)
+	}
+}
+
+func TestJellyfinScrobble(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		watched bool
+	}{
+		{
+			name:    "played to completion",
+			payload: `{"NotificationType": "PlaybackStop", "ItemType": "Episode", "SeriesName": "The Office", "SeasonNumber": 2, "EpisodeNumber": 5, "PlayedToCompletion": true, "UtcTimestamp": "2023-05-01T12:00:00.1234567Z", "NotificationUsername": "alice"}`,
+			watched: true,
+		},
+		{
+			name:    "marked as played",
+			payload: `{"NotificationType": "UserDataSaved", "ItemType": "Episode", "SeriesName": "The Office", "SeasonNumber": 2, "EpisodeNumber": 5, "Played": true}`,
+			watched: true,
+		},
+		{
+			name:    "stopped halfway",
+			payload: `{"NotificationType": "PlaybackStop", "ItemType": "Episode", "SeriesName": "The Office", "SeasonNumber": 2, "EpisodeNumber": 5, "PlayedToCompletion": false}`,
+		},
+		{
+			name:    "movie",
+			payload: `{"NotificationType": "PlaybackStop", "ItemType": "Movie", "PlayedToCompletion": true}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler, events := newTestHandler("secret")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jellyfin?token=secret", strings.NewReader(test.payload)))
+
+			if !test.watched {
+				if recorder.Code != http.StatusOK || len(*events) != 0 {
+					t.Errorf("status = %d, events = %+v, want the event to be ignored", recorder.Code, *events)
+				}
+				return
+			}
+			if recorder.Code != http.StatusAccepted || len(*events) != 1 {
+				t.Fatalf("status = %d, events = %+v, want one accepted event", recorder.Code, *events)
+			}
+			event := (*events)[0]
+			if event.Source != SourceJellyfin || event.Show != "The Office" || event.Season != 2 || event.Episode != 5 || event.WatchedAt.IsZero() {
+				t.Errorf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestGenericScrobble(t *testing.T) {
+	handler, events := newTestHandler("secret")
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/generic?token=secret", strings.NewReader(`{"path": "/media/The Office/Season 2/S02E05.mkv"}`)))
+	if recorder.Code != http.StatusAccepted || len(*events) != 1 || (*events)[0].Path != "/media/The Office/Season 2/S02E05.mkv" {
+		t.Fatalf("status = %d, events = %+v, want the event to be accepted", recorder.Code, *events)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/generic?token=secret", strings.NewReader(`{"show": "The Office"}`)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d for an incomplete event", recorder.Code, http.StatusBadRequest)
+	}
+}
+
+func TestToken(t *testing.T) {
+	handler, _ := newTestHandler("secret")
+	payload := `{"show": "The Office", "season": 2, "episode": 5}`
+
+	tests := []struct {
+		name   string
+		url    string
+		header string
+		want   int
+	}{
+		{name: "missing", url: "/generic", want: http.StatusUnauthorized},
+		{name: "wrong", url: "/generic?token=wrong", want: http.StatusUnauthorized},
+		{name: "query parameter", url: "/generic?token=secret", want: http.StatusAccepted},
+		{name: "bearer token", url: "/generic", header: "Bearer secret", want: http.StatusAccepted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, test.url, strings.NewReader(payload))
+			if test.header != "" {
+				request.Header.Set("Authorization", test.header)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			if recorder.Code != test.want {
+				t.Errorf("status = %d, want %d", recorder.Code, test.want)
+			}
+		})
+	}
+}
+
+func TestWithoutTokenEveryRequestIsUnauthorized(t *testing.T) {
+	handler, events := newTestHandler("")
+
+	for _, url := range []string{"/generic", "/generic?token="} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, url, strings.NewReader(`{"show": "The Office", "season": 2, "episode": 5}`)))
+		if recorder.Code != http.StatusUnauthorized {
+			t.Errorf("status of %s = %d, want %d", url, recorder.Code, http.StatusUnauthorized)
+		}
+	}
+	if len(*events) != 0 {
+		t.Errorf("events = %+v, want none", *events)
+	}
+}
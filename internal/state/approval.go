@@ -0,0 +1,196 @@
+package state
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var approvalsBucket = []byte("approvals")
+
+// ApprovalStatus is the status of a deletion that requires approval
+type ApprovalStatus string
+
+const (
+	// ApprovalPending indicates that the deletion has not been reviewed yet
+	ApprovalPending ApprovalStatus = "pending"
+	// ApprovalApproved indicates that the file may be deleted
+	ApprovalApproved ApprovalStatus = "approved"
+	// ApprovalRejected indicates that the file must be kept
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
+// Approval is a deletion that requires approval
+type Approval struct {
+	Path        string         `json:"path"`
+	Folder      string         `json:"folder"`
+	Show        string         `json:"show"`
+	TraktID     int            `json:"traktId,omitempty"`
+	Season      int            `json:"season"`
+	Episode     int            `json:"episode"`
+	Size        int64          `json:"size"`
+	LastWatched time.Time      `json:"lastWatched"`
+	Plays       int            `json:"plays"`
+	Status      ApprovalStatus `json:"status"`
+	RequestedAt time.Time      `json:"requestedAt"`
+	DecidedAt   *time.Time     `json:"decidedAt,omitempty"`
+}
+
+// Approvals returns all deletions that require approval, keyed by path
+func (store *Store) Approvals() (map[string]Approval, error) {
+	approvals := map[string]Approval{}
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(approvalsBucket)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			var approval Approval
+			if err := json.Unmarshal(value, &approval); err != nil {
+				return err
+			}
+			approvals[approval.Path] = approval
+			return nil
+		})
+	})
+	return approvals, err
+}
+
+// SetPendingApprovals registers the deletions that currently require approval.
+// Already reviewed deletions keep their status, and pending deletions that are no longer
+// candidates, for example because the file was removed, are dropped. When processed is not nil,
+// only the pending deletions of the paths in processed are dropped.
+func (store *Store) SetPendingApprovals(approvals []Approval, processed map[string]bool) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(approvalsBucket)
+
+		current := map[string]bool{}
+		for _, approval := range approvals {
+			current[approval.Path] = true
+
+			existing, err := getApproval(bucket, approval.Path)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				approval.Status = existing.Status
+				approval.RequestedAt = existing.RequestedAt
+				approval.DecidedAt = existing.DecidedAt
+			} else {
+				approval.Status = ApprovalPending
+			}
+
+			if err := putApproval(bucket, approval); err != nil {
+				return err
+			}
+		}
+
+		var stale [][]byte
+		err := bucket.ForEach(func(key, value []byte) error {
+			var approval Approval
+			if err := json.Unmarshal(value, &approval); err != nil {
+				return err
+			}
+			if approval.Status == ApprovalPending && !current[approval.Path] && (processed == nil || processed[approval.Path]) {
+				stale = append(stale, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range stale {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DecideApprovals sets the status of all deletions for which match returns true and
+// returns the number of deletions that were updated
+func (store *Store) DecideApprovals(match func(Approval) bool, status ApprovalStatus, decidedAt time.Time) (int, error) {
+	updated := 0
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(approvalsBucket)
+
+		var approvals []Approval
+		err := bucket.ForEach(func(key, value []byte) error {
+			var approval Approval
+			if err := json.Unmarshal(value, &approval); err != nil {
+				return err
+			}
+			if match(approval) {
+				approvals = append(approvals, approval)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, approval := range approvals {
+			approval.Status = status
+			approval.DecidedAt = &decidedAt
+			if status == ApprovalPending {
+				approval.DecidedAt = nil
+			}
+			if err := putApproval(bucket, approval); err != nil {
+				return err
+			}
+			updated++
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// RemoveApprovals drops the reviewed and pending deletions of the show in folder
+func (store *Store) RemoveApprovals(folder string) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		approvals := tx.Bucket(approvalsBucket)
+		var keys [][]byte
+		err := approvals.ForEach(func(key, value []byte) error {
+			var approval Approval
+			if err := json.Unmarshal(value, &approval); err != nil {
+				return err
+			}
+			if approval.Folder == folder {
+				keys = append(keys, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := approvals.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func getApproval(bucket *bolt.Bucket, path string) (*Approval, error) {
+	value := bucket.Get([]byte(path))
+	if value == nil {
+		return nil, nil
+	}
+
+	var approval Approval
+	if err := json.Unmarshal(value, &approval); err != nil {
+		return nil, err
+	}
+	return &approval, nil
+}
+
+func putApproval(bucket *bolt.Bucket, approval Approval) error {
+	value, err := json.Marshal(approval)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(approval.Path), value)
+}
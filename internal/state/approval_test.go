@@ -0,0 +1,94 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetPendingApprovalsKeepsReviewedStatus(t *testing.T) {
+	store := openTestStore(t)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := Approval{Path: "/media/Show/Season 1/S01E01.mkv", Folder: "Show", Season: 1, Episode: 1, RequestedAt: now}
+	second := Approval{Path: "/media/Show/Season 1/S01E02.mkv", Folder: "Show", Season: 1, Episode: 2, RequestedAt: now}
+	if err := store.SetPendingApprovals([]Approval{first, second}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := store.DecideApprovals(func(approval Approval) bool {
+		return approval.Path == first.Path
+	}, ApprovalApproved, now)
+	if err != nil || updated != 1 {
+		t.Fatalf("DecideApprovals() = %v, %v, want 1 update", updated, err)
+	}
+
+	// The second episode is no longer a candidate, the first one is requested again
+	first.RequestedAt = now.Add(time.Hour)
+	if err := store.SetPendingApprovals([]Approval{first}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	approvals, err := store.Approvals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approvals) != 1 {
+		t.Fatalf("got %d approvals, want 1", len(approvals))
+	}
+	if got := approvals[first.Path]; got.Status != ApprovalApproved || !got.RequestedAt.Equal(now) {
+		t.Errorf("approval = %+v, want the approved status and original request time", got)
+	}
+
+	// Deleting the file removes its approval
+	if err := store.RecordDecisions([]Decision{{Path: first.Path, Action: "deleted", Time: now, Deleted: true}}); err != nil {
+		t.Fatal(err)
+	}
+	approvals, err = store.Approvals()
+	if err != nil || len(approvals) != 0 {
+		t.Errorf("Approvals() = %v, %v, want none after deletion", approvals, err)
+	}
+}
+
+func TestRemoveApprovals(t *testing.T) {
+	store := openTestStore(t)
+
+	err := store.SetPendingApprovals([]Approval{
+		{Path: "/media/Show/Season 1/S01E01.mkv", Folder: "Show"},
+		{Path: "/media/Other/Season 1/S01E01.mkv", Folder: "Other"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveApprovals("Show"); err != nil {
+		t.Fatal(err)
+	}
+
+	approvals, err := store.Approvals()
+	if err != nil || len(approvals) != 1 {
+		t.Fatalf("Approvals() = %v, %v, want only the approval of Other", approvals, err)
+	}
+}
+
+func TestSetPendingApprovalsOnlyDropsProcessedPaths(t *testing.T) {
+	store := openTestStore(t)
+
+	first := Approval{Path: "/media/Show/Season 1/S01E01.mkv", Folder: "Show"}
+	second := Approval{Path: "/media/Show/Season 1/S01E02.mkv", Folder: "Show"}
+	if err := store.SetPendingApprovals([]Approval{first, second}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the first episode was processed and is no longer a candidate
+	if err := store.SetPendingApprovals(nil, map[string]bool{first.Path: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	approvals, err := store.Approvals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := approvals[second.Path]; len(approvals) != 1 || !ok {
+		t.Errorf("Approvals() = %v, want only the approval of the second episode", approvals)
+	}
+}
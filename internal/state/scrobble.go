@@ -0,0 +1,71 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var scrobblesBucket = []byte("scrobbles")
+
+// Scrobble is an episode that was reported as watched by a media server
+type Scrobble struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Show    string    `json:"show"`
+	Season  int       `json:"season"`
+	Episode int       `json:"episode"`
+	// User is the user of the media server who watched the episode, when it was reported
+	User string `json:"user,omitempty"`
+	// Paths are the TV show files of the episode
+	Paths []string `json:"paths"`
+}
+
+// AddScrobble records a watched episode and removes the scrobbles from before pruneBefore
+func (store *Store) AddScrobble(scrobble Scrobble, pruneBefore time.Time) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scrobblesBucket)
+
+		var expired [][]byte
+		cursor := bucket.Cursor()
+		prefix := eventKeyPrefix(pruneBefore)
+		for key, _ := cursor.First(); key != nil && bytes.Compare(key[:8], prefix) < 0; key, _ = cursor.Next() {
+			expired = append(expired, append([]byte(nil), key...))
+		}
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		value, err := json.Marshal(scrobble)
+		if err != nil {
+			return err
+		}
+		return putSequenced(bucket, scrobble.Time, value)
+	})
+}
+
+// Scrobbles returns the watched episodes since the given time, oldest first
+func (store *Store) Scrobbles(since time.Time) ([]Scrobble, error) {
+	var scrobbles []Scrobble
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scrobblesBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek(eventKeyPrefix(since)); key != nil; key, value = cursor.Next() {
+			var scrobble Scrobble
+			if err := json.Unmarshal(value, &scrobble); err != nil {
+				return err
+			}
+			scrobbles = append(scrobbles, scrobble)
+		}
+		return nil
+	})
+	return scrobbles, err
+}
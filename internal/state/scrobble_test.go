@@ -0,0 +1,40 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScrobbles(t *testing.T) {
+	store := openTestStore(t)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	old := Scrobble{Time: now.Add(-48 * time.Hour), Source: "plex", Show: "Show", Season: 1, Episode: 1}
+	recent := Scrobble{Time: now.Add(-time.Hour), Source: "plex", Show: "Show", Season: 1, Episode: 2, Paths: []string{"/media/Show/Season 1/S01E02.mkv"}}
+	for _, scrobble := range []Scrobble{old, recent} {
+		if err := store.AddScrobble(scrobble, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scrobbles, err := store.Scrobbles(now.Add(-2 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scrobbles) != 1 || scrobbles[0].Episode != 2 || len(scrobbles[0].Paths) != 1 {
+		t.Fatalf("Scrobbles() = %+v, want only the recent scrobble", scrobbles)
+	}
+
+	// Adding a scrobble prunes the expired ones
+	latest := Scrobble{Time: now, Source: "jellyfin", Show: "Show", Season: 1, Episode: 3}
+	if err := store.AddScrobble(latest, now.Add(-24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	scrobbles, err = store.Scrobbles(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scrobbles) != 2 || scrobbles[0].Episode != 2 || scrobbles[1].Episode != 3 {
+		t.Errorf("Scrobbles() = %+v, want the recent and latest scrobbles", scrobbles)
+	}
+}
@@ -0,0 +1,324 @@
+// Package state implements the database in which series-cleanup keeps track of the
+// files it has seen and what it did with them across runs
+package state
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// lockTimeout is how long to wait for another process, such as a running daemon, to release the database
+const lockTimeout = 10 * time.Second
+
+var (
+	filesBucket  = []byte("files")
+	eventsBucket = []byte("events")
+)
+
+// File is the recorded state of a TV show file
+type File struct {
+	Path       string     `json:"path"`
+	ScanFolder string     `json:"scanFolder"`
+	Show       string     `json:"show"`
+	Season     int        `json:"season"`
+	Episode    int        `json:"episode"`
+	Size       int64      `json:"size"`
+	FirstSeen  time.Time  `json:"firstSeen"`
+	LastSeen   time.Time  `json:"lastSeen"`
+	Action     string     `json:"action,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	DecidedAt  *time.Time `json:"decidedAt,omitempty"`
+	DeletedAt  *time.Time `json:"deletedAt,omitempty"`
+}
+
+// SeenFile describes a TV show file that was found during a scan
+type SeenFile struct {
+	Path       string
+	ScanFolder string
+	Show       string
+	Season     int
+	Episode    int
+	Size       int64
+	ModTime    time.Time
+}
+
+// Decision describes what was done with a TV show file
+type Decision struct {
+	Path       string
+	ScanFolder string
+	Show       string
+	Season     int
+	Episode    int
+	Size       int64
+	Action     string
+	Reason     string
+	Time       time.Time
+	// Deleted indicates that the file was removed from disk
+	Deleted bool
+}
+
+// Event is a change in the decision for a TV show file
+type Event struct {
+	Time       time.Time `json:"time"`
+	Path       string    `json:"path"`
+	ScanFolder string    `json:"scanFolder"`
+	Show       string    `json:"show"`
+	Season     int       `json:"season"`
+	Episode    int       `json:"episode"`
+	Size       int64     `json:"size"`
+	Action     string    `json:"action"`
+	Reason     string    `json:"reason"`
+}
+
+// Store is the state database
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens the state database at path, creating it if it does not exist yet
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: lockTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{filesBucket, eventsBucket, approvalsBucket, scrobblesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// OpenReadOnly opens an existing state database at path for reading
+func OpenReadOnly(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: lockTimeout, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the state database
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// SeeFiles records that files were found during a scan at seenAt and returns when each of
+// them was first seen, keyed by path.
+// Files that are not known yet are considered to be first seen when they were last modified,
+// so files that already existed before the database was created are not treated as new.
+func (store *Store) SeeFiles(files []SeenFile, seenAt time.Time) (map[string]time.Time, error) {
+	firstSeen := make(map[string]time.Time, len(files))
+
+	err := store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(filesBucket)
+		for _, seen := range files {
+			file, err := getFile(bucket, seen.Path)
+			if err != nil {
+				return err
+			}
+
+			// A file that reappears after it was deleted is a new download
+			if file == nil || file.DeletedAt != nil {
+				file = &File{FirstSeen: seenAt}
+				if !seen.ModTime.IsZero() && seen.ModTime.Before(seenAt) {
+					file.FirstSeen = seen.ModTime
+				}
+			}
+
+			file.Path = seen.Path
+			file.ScanFolder = seen.ScanFolder
+			file.Show = seen.Show
+			file.Season = seen.Season
+			file.Episode = seen.Episode
+			file.Size = seen.Size
+			file.LastSeen = seenAt
+
+			if err := putFile(bucket, file); err != nil {
+				return err
+			}
+			firstSeen[seen.Path] = file.FirstSeen
+		}
+		return nil
+	})
+
+	return firstSeen, err
+}
+
+// RecordDecisions records what was done with files.
+// An event is added for every file of which the decision changed, and for every deletion.
+func (store *Store) RecordDecisions(decisions []Decision) error {
+	return store.db.Update(func(tx *bolt.Tx) error {
+		files := tx.Bucket(filesBucket)
+		events := tx.Bucket(eventsBucket)
+
+		for _, decision := range decisions {
+			file, err := getFile(files, decision.Path)
+			if err != nil {
+				return err
+			}
+			if file == nil {
+				file = &File{Path: decision.Path, FirstSeen: decision.Time, LastSeen: decision.Time}
+			}
+			file.ScanFolder = decision.ScanFolder
+			file.Show = decision.Show
+			file.Season = decision.Season
+			file.Episode = decision.Episode
+			file.Size = decision.Size
+
+			changed := decision.Deleted || file.Action != decision.Action || file.Reason != decision.Reason
+
+			decidedAt := decision.Time
+			file.Action = decision.Action
+			file.Reason = decision.Reason
+			file.DecidedAt = &decidedAt
+			if decision.Deleted {
+				file.DeletedAt = &decidedAt
+				if err := tx.Bucket(approvalsBucket).Delete([]byte(decision.Path)); err != nil {
+					return err
+				}
+			}
+
+			if err := putFile(files, file); err != nil {
+				return err
+			}
+
+			if !changed {
+				continue
+			}
+
+			err = addEvent(events, Event{
+				Time:       decision.Time,
+				Path:       file.Path,
+				ScanFolder: file.ScanFolder,
+				Show:       file.Show,
+				Season:     file.Season,
+				Episode:    file.Episode,
+				Size:       file.Size,
+				Action:     decision.Action,
+				Reason:     decision.Reason,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// File returns the recorded state of the file at path, or nil if it was never seen
+func (store *Store) File(path string) (*File, error) {
+	var file *File
+	err := store.db.View(func(tx *bolt.Tx) error {
+		var err error
+		file, err = getFile(tx.Bucket(filesBucket), path)
+		return err
+	})
+	return file, err
+}
+
+// Files returns the recorded state of all files that were ever seen
+func (store *Store) Files() ([]File, error) {
+	var files []File
+	err := store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(filesBucket).ForEach(func(_, value []byte) error {
+			var file File
+			if err := json.Unmarshal(value, &file); err != nil {
+				return err
+			}
+			files = append(files, file)
+			return nil
+		})
+	})
+	return files, err
+}
+
+// Events returns the recorded events since the given time, oldest first
+func (store *Store) Events(since time.Time) ([]Event, error) {
+	var events []Event
+	err := store.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(eventsBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek(eventKeyPrefix(since)); key != nil; key, value = cursor.Next() {
+			var event Event
+			if err := json.Unmarshal(value, &event); err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	return events, err
+}
+
+func getFile(bucket *bolt.Bucket, path string) (*File, error) {
+	if bucket == nil {
+		return nil, errors.New("state database is not initialized")
+	}
+
+	value := bucket.Get([]byte(path))
+	if value == nil {
+		return nil, nil
+	}
+
+	var file File
+	if err := json.Unmarshal(value, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+func putFile(bucket *bolt.Bucket, file *File) error {
+	value, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(file.Path), value)
+}
+
+func addEvent(bucket *bolt.Bucket, event Event) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return putSequenced(bucket, event.Time, value)
+}
+
+// putSequenced stores value under a key that sorts by t, made unique by the bucket sequence
+func putSequenced(bucket *bolt.Bucket, t time.Time, value []byte) error {
+	sequence, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	key := make([]byte, 16)
+	copy(key, eventKeyPrefix(t))
+	binary.BigEndian.PutUint64(key[8:], sequence)
+	return bucket.Put(key, value)
+}
+
+func eventKeyPrefix(t time.Time) []byte {
+	key := make([]byte, 8)
+	if t.IsZero() {
+		return key
+	}
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
@@ -0,0 +1,102 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSeeFilesKeepsFirstSeen(t *testing.T) {
+	store := openTestStore(t)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	modified := now.Add(-48 * time.Hour)
+
+	files := []SeenFile{
+		{Path: "/media/Show/S01E01.mkv", Show: "Show", Season: 1, Episode: 1, ModTime: modified},
+		{Path: "/media/Show/S01E02.mkv", Show: "Show", Season: 1, Episode: 2, ModTime: now.Add(time.Hour)},
+	}
+
+	firstSeen, err := store.SeeFiles(files, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := firstSeen[files[0].Path]; !got.Equal(modified) {
+		t.Errorf("first seen of existing file = %v, want modification time %v", got, modified)
+	}
+	if got := firstSeen[files[1].Path]; !got.Equal(now) {
+		t.Errorf("first seen of file modified in the future = %v, want %v", got, now)
+	}
+
+	firstSeen, err = store.SeeFiles(files, now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := firstSeen[files[0].Path]; !got.Equal(modified) {
+		t.Errorf("first seen after second scan = %v, want %v", got, modified)
+	}
+}
+
+func TestRecordDecisions(t *testing.T) {
+	store := openTestStore(t)
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	path := "/media/Show/S01E01.mkv"
+
+	if _, err := store.SeeFiles([]SeenFile{{Path: path, Show: "Show", Season: 1, Episode: 1}}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	decisions := []Decision{
+		{Path: path, Show: "Show", Season: 1, Episode: 1, Action: "kept", Reason: "too recent", Time: now},
+		{Path: path, Show: "Show", Season: 1, Episode: 1, Action: "kept", Reason: "too recent", Time: now.Add(time.Hour)},
+		{Path: path, Show: "Show", Season: 1, Episode: 1, Action: "deleted", Reason: "watched", Time: now.Add(2 * time.Hour), Deleted: true},
+	}
+	for _, decision := range decisions {
+		if err := store.RecordDecisions([]Decision{decision}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := store.Events(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Action != "kept" || events[1].Action != "deleted" {
+		t.Fatalf("events = %+v, want a kept and a deleted event", events)
+	}
+
+	events, err = store.Events(now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Action != "deleted" {
+		t.Errorf("events since = %+v, want only the deleted event", events)
+	}
+
+	file, err := store.File(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.DeletedAt == nil || !file.DeletedAt.Equal(now.Add(2*time.Hour)) {
+		t.Errorf("DeletedAt = %v, want %v", file.DeletedAt, now.Add(2*time.Hour))
+	}
+
+	// A file that is downloaded again after it was deleted is seen for the first time again
+	redownloaded := now.Add(72 * time.Hour)
+	firstSeen, err := store.SeeFiles([]SeenFile{{Path: path}}, redownloaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := firstSeen[path]; !got.Equal(redownloaded) {
+		t.Errorf("first seen after download = %v, want %v", got, redownloaded)
+	}
+}
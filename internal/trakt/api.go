@@ -2,32 +2,65 @@ package trakt
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
+
+	"go.uber.org/zap"
 )
 
-const apiURL = "https://api.trakt.tv"
+// apiURL is a variable so tests can send the requests to a stand-in server
+var apiURL = "https://api.trakt.tv"
+
 const apiVersion = "2"
 
 const apiDefaultDatapath = "/data"
 
+// ErrUnavailable is returned when the Trakt API could not be reached or reported a server-side failure
+var ErrUnavailable = errors.New("trakt API is unavailable")
+
+// RequestObserver is called after every request to the Trakt API.
+// The endpoint is the request path with user names and query parameters removed,
+// statusCode is 0 if no response was received.
+type RequestObserver func(endpoint string, statusCode int, duration time.Duration)
+
 // API represents the Trakt API
 type API struct {
 	DataPath        string
 	ClientID        string
 	ClientSecret    string
 	IsAuthenticated bool
-	accessToken     string
+	// TokenExpiresAt is when the current access token expires
+	TokenExpiresAt time.Time
+	// RequestObserver is optional and is notified of every request made
+	RequestObserver RequestObserver
+	// Logger is optional and logs every request made at debug level
+	Logger      *zap.Logger
+	accessToken string
 }
 
 type apiResponse struct {
 	StatusCode int
+	Header     http.Header
 	Body       []byte
 }
 
+func (response *apiResponse) checkStatus() error {
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return nil
+	case response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500:
+		return fmt.Errorf("%w: status code %v", ErrUnavailable, response.StatusCode)
+	default:
+		return fmt.Errorf("unexpected status code %v", response.StatusCode)
+	}
+}
+
 func (api *API) validate() error {
 	if api.DataPath == "" {
 		api.DataPath = apiDefaultDatapath
@@ -36,7 +69,7 @@ func (api *API) validate() error {
 	return nil
 }
 
-func (api *API) sendRequest(method, url string, payload interface{}) (*apiResponse, error) {
+func (api *API) sendRequest(ctx context.Context, method, url string, payload interface{}) (*apiResponse, error) {
 	if method == "" {
 		method = "GET"
 	}
@@ -60,7 +93,7 @@ func (api *API) sendRequest(method, url string, payload interface{}) (*apiRespon
 		Timeout: time.Second * 5, // Timeout after 5 seconds
 	}
 
-	req, err := http.NewRequest(method, requestURL, bytes.NewReader(reqPayload))
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(reqPayload))
 	if err != nil {
 		return nil, err
 	}
@@ -72,9 +105,28 @@ func (api *API) sendRequest(method, url string, payload interface{}) (*apiRespon
 		req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", api.accessToken))
 	}
 
+	start := time.Now()
 	response, err := apiClient.Do(req)
+	statusCode := 0
+	if response != nil {
+		statusCode = response.StatusCode
+	}
+	if api.RequestObserver != nil {
+		api.RequestObserver(endpointLabel(url), statusCode, time.Since(start))
+	}
+	if api.Logger != nil {
+		api.Logger.Debug("Sent request to Trakt API",
+			zap.String("method", method),
+			zap.String("endpoint", endpointLabel(url)),
+			zap.Int("statusCode", statusCode),
+			zap.Duration("duration", time.Since(start)),
+		)
+	}
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
 	}
 
 	if response.Body != nil {
@@ -88,7 +140,21 @@ func (api *API) sendRequest(method, url string, payload interface{}) (*apiRespon
 
 	var returnVal = apiResponse{}
 	returnVal.StatusCode = response.StatusCode
+	returnVal.Header = response.Header
 	returnVal.Body = body
 
 	return &returnVal, nil
 }
+
+// endpointLabel returns the path of a request url without the query and with user names and show ids replaced by a placeholder
+func endpointLabel(url string) string {
+	path, _, _ := strings.Cut(url, "?")
+	segments := strings.Split(path, "/")
+	if len(segments) > 2 && segments[1] == "users" && segments[2] != "settings" {
+		segments[2] = ":user"
+	}
+	if len(segments) > 2 && segments[1] == "shows" {
+		segments[2] = ":id"
+	}
+	return strings.Join(segments, "/")
+}
@@ -2,6 +2,7 @@
 package trakt
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -29,13 +30,13 @@ type deviceCode struct {
 	Interval        int    `json:"interval"`
 }
 
-func (code *deviceCode) ExchangeForAccessToken(api *API) (*accessToken, error) {
+func (code *deviceCode) ExchangeForAccessToken(ctx context.Context, api *API) (*accessToken, error) {
 	deviceCodePollPayload := deviceCodePollPayload{}
 	deviceCodePollPayload.Code = code.DeviceCode
 	deviceCodePollPayload.ClientID = api.ClientID
 	deviceCodePollPayload.ClientSecret = api.ClientSecret
 
-	result, err := api.sendRequest(http.MethodPost, "/oauth/device/token", deviceCodePollPayload)
+	result, err := api.sendRequest(ctx, http.MethodPost, "/oauth/device/token", deviceCodePollPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +119,7 @@ func (token *accessToken) WillExpireSoon() bool {
 	return accessTokenExpirationDateWithBuffer.Sub(currentTime) < 0
 }
 
-func (token *accessToken) Refresh(api *API) (*accessToken, error) {
+func (token *accessToken) Refresh(ctx context.Context, api *API) (*accessToken, error) {
 	accessTokenRefreshPayload := accessTokenRefreshPayload{}
 	accessTokenRefreshPayload.RefreshToken = token.RefreshToken
 	accessTokenRefreshPayload.ClientID = api.ClientID
@@ -126,7 +127,7 @@ func (token *accessToken) Refresh(api *API) (*accessToken, error) {
 	accessTokenRefreshPayload.RedirectURI = "urn:ietf:wg:oauth:2.0:oob"
 	accessTokenRefreshPayload.GrantType = "refresh_token"
 
-	result, err := api.sendRequest(http.MethodPost, "/oauth/token", accessTokenRefreshPayload)
+	result, err := api.sendRequest(ctx, http.MethodPost, "/oauth/token", accessTokenRefreshPayload)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +148,7 @@ func getAccessTokenDataFromAPIResponse(response *apiResponse) (*accessToken, err
 	}
 }
 
-func authenticateFromFile(file string, api *API) (*accessToken, error) {
+func authenticateFromFile(ctx context.Context, file string, api *API) (*accessToken, error) {
 	accessTokenData := &accessToken{}
 	err := accessTokenData.ReadFromFile(file)
 	if err != nil {
@@ -159,12 +160,12 @@ func authenticateFromFile(file string, api *API) (*accessToken, error) {
 		if err != nil {
 			return nil, err
 		}
-		accessTokenData, err = authenticateWithDeviceToken(api)
+		accessTokenData, err = authenticateWithDeviceToken(ctx, api)
 		if err != nil {
 			return nil, err
 		}
 	} else if accessTokenData.WillExpireSoon() {
-		accessTokenData, err = accessTokenData.Refresh(api)
+		accessTokenData, err = accessTokenData.Refresh(ctx, api)
 		if err != nil {
 			return nil, err
 		}
@@ -173,8 +174,8 @@ func authenticateFromFile(file string, api *API) (*accessToken, error) {
 	return accessTokenData, nil
 }
 
-func authenticateWithDeviceToken(api *API) (*accessToken, error) {
-	deviceCode, err := getDeviceToken(api)
+func authenticateWithDeviceToken(ctx context.Context, api *API) (*accessToken, error) {
+	deviceCode, err := getDeviceToken(ctx, api)
 	if err != nil {
 		return nil, err
 	}
@@ -189,7 +190,7 @@ func authenticateWithDeviceToken(api *API) (*accessToken, error) {
 
 	waitTimer := 0
 	for waitTimer < deviceCode.ExpiresIn {
-		accessTokenData, err := deviceCode.ExchangeForAccessToken(api)
+		accessTokenData, err := deviceCode.ExchangeForAccessToken(ctx, api)
 		if err != nil {
 			return nil, err
 		}
@@ -199,17 +200,21 @@ func authenticateWithDeviceToken(api *API) (*accessToken, error) {
 		}
 
 		waitTimer = waitTimer + deviceCode.Interval
-		time.Sleep(time.Duration(deviceCode.Interval) * time.Second)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Duration(deviceCode.Interval) * time.Second):
+		}
 	}
 
 	return nil, fmt.Errorf("could not exchange device code for access token")
 }
 
-func getDeviceToken(api *API) (*deviceCode, error) {
+func getDeviceToken(ctx context.Context, api *API) (*deviceCode, error) {
 	deviceCodePayload := deviceCodePayload{}
 	deviceCodePayload.ClientID = api.ClientID
 
-	result, err := api.sendRequest(http.MethodPost, "/oauth/device/code", deviceCodePayload)
+	result, err := api.sendRequest(ctx, http.MethodPost, "/oauth/device/code", deviceCodePayload)
 	if err != nil {
 		return nil, err
 	}
@@ -222,7 +227,7 @@ func getDeviceToken(api *API) (*deviceCode, error) {
 }
 
 // Authenticate against the Trakt API
-func (api *API) Authenticate() error {
+func (api *API) Authenticate(ctx context.Context) error {
 	err := api.validate()
 	if err != nil {
 		return err
@@ -232,12 +237,12 @@ func (api *API) Authenticate() error {
 	var accessToken *accessToken
 
 	if helpers.FileExists(authDatafile) {
-		accessToken, err = authenticateFromFile(authDatafile, api)
+		accessToken, err = authenticateFromFile(ctx, authDatafile, api)
 		if err != nil {
 			return err
 		}
 	} else {
-		accessToken, err = authenticateWithDeviceToken(api)
+		accessToken, err = authenticateWithDeviceToken(ctx, api)
 		if err != nil {
 			return err
 		}
@@ -250,8 +255,58 @@ func (api *API) Authenticate() error {
 		}
 		api.accessToken = accessToken.AccessToken
 		api.IsAuthenticated = true
+		api.TokenExpiresAt = time.Unix(accessToken.CreatedAt+accessToken.ExpiresIn, 0)
 		return nil
 	}
 
 	return nil
 }
+
+// ClearAuthentication removes the stored access token, so the next authentication
+// goes through the device authorization flow again
+func (api *API) ClearAuthentication() error {
+	err := api.validate()
+	if err != nil {
+		return err
+	}
+
+	var authDatafile = api.DataPath + "/trakt.json"
+	if !helpers.FileExists(authDatafile) {
+		return nil
+	}
+
+	api.accessToken = ""
+	api.IsAuthenticated = false
+	return os.Remove(authDatafile)
+}
+
+// UserSettings represents the settings of the authenticated Trakt user
+type UserSettings struct {
+	User struct {
+		Username string `json:"username"`
+		Name     string `json:"name"`
+		VIP      bool   `json:"vip"`
+		IDS      struct {
+			Slug string `json:"slug"`
+		} `json:"ids"`
+	} `json:"user"`
+}
+
+// GetUserSettings returns the settings of the authenticated Trakt user
+func (api *API) GetUserSettings(ctx context.Context) (*UserSettings, error) {
+	result, err := api.sendRequest(ctx, http.MethodGet, "/users/settings", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := result.checkStatus(); err != nil {
+		return nil, err
+	}
+
+	settings := UserSettings{}
+	err = json.Unmarshal(result.Body, &settings)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
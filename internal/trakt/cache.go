@@ -0,0 +1,80 @@
+package trakt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+type lastActivities struct {
+	All      time.Time `json:"all"`
+	Episodes struct {
+		WatchedAt time.Time `json:"watched_at"`
+	} `json:"episodes"`
+}
+
+func getLastActivities(ctx context.Context, api API) (*lastActivities, error) {
+	result, err := api.sendRequest(ctx, http.MethodGet, "/sync/last_activities", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := result.checkStatus(); err != nil {
+		return nil, err
+	}
+
+	activities := lastActivities{}
+	err = json.Unmarshal(result.Body, &activities)
+	if err != nil {
+		return nil, err
+	}
+	return &activities, nil
+}
+
+type watchedShowsCache struct {
+	User              string        `json:"user"`
+	EpisodesWatchedAt time.Time     `json:"episodes_watched_at"`
+	SyncedAt          time.Time     `json:"synced_at"`
+	WatchedShows      []WatchedShow `json:"watched_shows"`
+}
+
+func watchedShowsCacheFile(api API, user string) string {
+	return fmt.Sprintf("%v/watched_shows_%v.json", api.DataPath, user)
+}
+
+func (cache *watchedShowsCache) WriteToFile(path string) error {
+	jsonString, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, jsonString, 0o600)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (cache *watchedShowsCache) ReadFromFile(path string) error {
+	if !helpers.FileExists(path) {
+		return fmt.Errorf("file %v does not exist", path)
+	}
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(file, &cache)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
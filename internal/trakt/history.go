@@ -0,0 +1,316 @@
+package trakt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+const historyPageLimit = 100
+
+// Actions of history items, which tell how a play was added to the history
+const (
+	// ActionScrobble is a play that was reported by a media player after it was watched
+	ActionScrobble = "scrobble"
+	// ActionCheckin is a play that was checked in when it started, whether it was finished or not
+	ActionCheckin = "checkin"
+	// ActionWatch is a play that was marked as watched by the user
+	ActionWatch = "watch"
+)
+
+// HistoryItem represents a single play of an episode in the Trakt watch history
+type HistoryItem struct {
+	ID        int64     `json:"id"`
+	WatchedAt time.Time `json:"watched_at"`
+	Action    string    `json:"action"`
+	Episode   struct {
+		Season int `json:"season"`
+		Number int `json:"number"`
+	} `json:"episode"`
+	Show show `json:"show"`
+}
+
+type historyCache struct {
+	User string `json:"user"`
+	// EpisodesWatchedAt is when the watched episodes last changed according to Trakt
+	EpisodesWatchedAt time.Time `json:"episodes_watched_at"`
+	SyncedAt          time.Time `json:"synced_at"`
+	// FullSyncAt is when the complete history was last downloaded
+	FullSyncAt time.Time     `json:"full_sync_at"`
+	Items      []HistoryItem `json:"items"`
+}
+
+func historyCacheFile(api API, user string) string {
+	return fmt.Sprintf("%v/history_%v.json", api.DataPath, user)
+}
+
+func (cache *historyCache) WriteToFile(path string) error {
+	jsonString, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, jsonString, 0o600)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (cache *historyCache) ReadFromFile(path string) error {
+	if !helpers.FileExists(path) {
+		return fmt.Errorf("file %v does not exist", path)
+	}
+
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(file, &cache)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LatestWatchedAt returns the most recent play in the cached history
+func (cache *historyCache) LatestWatchedAt() time.Time {
+	var latest time.Time
+	for _, item := range cache.Items {
+		if item.WatchedAt.After(latest) {
+			latest = item.WatchedAt
+		}
+	}
+	return latest
+}
+
+// Merge adds items to the cached history, skipping plays that are already known
+func (cache *historyCache) Merge(items []HistoryItem) {
+	known := make(map[int64]bool, len(cache.Items))
+	for _, item := range cache.Items {
+		known[item.ID] = true
+	}
+
+	for _, item := range items {
+		if known[item.ID] {
+			continue
+		}
+		known[item.ID] = true
+		cache.Items = append(cache.Items, item)
+	}
+}
+
+// getHistoryPage returns a page of limit plays that were watched since startAt, the number of
+// pages and the number of plays on all pages
+func getHistoryPage(ctx context.Context, api API, userName string, startAt time.Time, page, limit int) ([]HistoryItem, int, int, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	if !startAt.IsZero() {
+		query.Set("start_at", startAt.UTC().Format(time.RFC3339))
+	}
+
+	result, err := api.sendRequest(ctx, http.MethodGet, fmt.Sprintf("/users/%v/history/episodes?%v", userName, query.Encode()), nil)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	if err := result.checkStatus(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	var items []HistoryItem
+	err = json.Unmarshal(result.Body, &items)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	pageCount, err := strconv.Atoi(result.Header.Get("X-Pagination-Page-Count"))
+	if err != nil {
+		pageCount = page
+	}
+	itemCount, err := strconv.Atoi(result.Header.Get("X-Pagination-Item-Count"))
+	if err != nil {
+		itemCount = -1
+	}
+
+	return items, pageCount, itemCount, nil
+}
+
+func getHistory(ctx context.Context, api API, userName string, startAt time.Time) ([]HistoryItem, error) {
+	var history []HistoryItem
+	for page, pageCount := 1, 1; page <= pageCount; page++ {
+		items, count, _, err := getHistoryPage(ctx, api, userName, startAt, page, historyPageLimit)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, items...)
+		pageCount = count
+	}
+	return history, nil
+}
+
+// getHistoryCount returns the number of plays in the history, or -1 when it is unknown
+func getHistoryCount(ctx context.Context, api API, userName string) (int, error) {
+	_, _, itemCount, err := getHistoryPage(ctx, api, userName, time.Time{}, 1, 1)
+	return itemCount, err
+}
+
+// GetWatchedShowsFromHistory builds the watched shows for this user from the Trakt watch history.
+// Unlike GetWatchedShows this includes the time each episode was first watched.
+// The history is cached in the API data path and is only downloaded again when Trakt reports that
+// the watched episodes have changed since the last sync. Then only plays newer than the cached
+// ones are downloaded, unless plays were removed or added with an earlier time, or the complete
+// history was last downloaded more than CacheMaxAge ago.
+func (user *User) GetWatchedShowsFromHistory(ctx context.Context, api API) error {
+	err := api.validate()
+	if err != nil {
+		return err
+	}
+
+	cacheFile := historyCacheFile(api, user.Name)
+	cache := &historyCache{}
+	if err := cache.ReadFromFile(cacheFile); err != nil || cache.User != user.Name {
+		cache = &historyCache{User: user.Name}
+	}
+	useCache := func(err error) error {
+		if cache.SyncedAt.IsZero() {
+			return err
+		}
+		return user.useCachedShowsAfterError(cache.SyncedAt, user.watchedShowsFromHistory(cache.Items), err)
+	}
+
+	activities, err := getLastActivities(ctx, api)
+	if err != nil {
+		return useCache(err)
+	}
+
+	fullSyncDue := cache.FullSyncAt.IsZero() || time.Since(cache.FullSyncAt) >= user.CacheMaxAge
+	if !fullSyncDue && cache.EpisodesWatchedAt.Equal(activities.Episodes.WatchedAt) {
+		user.setWatchedShows(user.watchedShowsFromHistory(cache.Items), true)
+		cache.SyncedAt = time.Now()
+		return cache.WriteToFile(cacheFile)
+	}
+
+	if !fullSyncDue {
+		items, err := getHistory(ctx, api, user.Name, cache.LatestWatchedAt())
+		if err != nil {
+			return useCache(err)
+		}
+		cache.Merge(items)
+
+		// Removed plays and plays added with an earlier time are not part of the new plays,
+		// but change the number of plays. When both happened the number can stay the same,
+		// which is left to the next full sync.
+		count, err := getHistoryCount(ctx, api, user.Name)
+		if err != nil {
+			return useCache(err)
+		}
+		fullSyncDue = count != len(cache.Items)
+	}
+
+	if fullSyncDue {
+		items, err := getHistory(ctx, api, user.Name, time.Time{})
+		if err != nil {
+			return useCache(err)
+		}
+		cache.Items = nil
+		cache.Merge(items)
+		cache.FullSyncAt = time.Now()
+	}
+
+	cache.EpisodesWatchedAt = activities.Episodes.WatchedAt
+	cache.SyncedAt = time.Now()
+
+	user.setWatchedShows(user.watchedShowsFromHistory(cache.Items), false)
+
+	return cache.WriteToFile(cacheFile)
+}
+
+// watchedShowsFromHistory builds the watched shows from the plays in the history.
+// Check-ins are left out when IgnoreCheckins is set.
+func (user *User) watchedShowsFromHistory(items []HistoryItem) []WatchedShow {
+	if !user.IgnoreCheckins {
+		return watchedShowsFromHistory(items)
+	}
+
+	plays := make([]HistoryItem, 0, len(items))
+	for _, item := range items {
+		if item.Action != ActionCheckin {
+			plays = append(plays, item)
+		}
+	}
+	return watchedShowsFromHistory(plays)
+}
+
+func watchedShowsFromHistory(items []HistoryItem) []WatchedShow {
+	type episodeKey struct {
+		show    int
+		season  int
+		episode int
+	}
+
+	shows := map[int]*WatchedShow{}
+	episodes := map[episodeKey]*Episode{}
+	var showOrder []int
+
+	for _, item := range items {
+		watchedShow, ok := shows[item.Show.IDS.Trakt]
+		if !ok {
+			watchedShow = &WatchedShow{Show: item.Show}
+			shows[item.Show.IDS.Trakt] = watchedShow
+			showOrder = append(showOrder, item.Show.IDS.Trakt)
+		}
+
+		key := episodeKey{item.Show.IDS.Trakt, item.Episode.Season, item.Episode.Number}
+		episode, ok := episodes[key]
+		if !ok {
+			episode = &Episode{Number: item.Episode.Number}
+			episodes[key] = episode
+		}
+
+		episode.Plays++
+		if episode.FirstWatched.IsZero() || item.WatchedAt.Before(episode.FirstWatched) {
+			episode.FirstWatched = item.WatchedAt
+		}
+		if item.WatchedAt.After(episode.LastWatched) {
+			episode.LastWatched = item.WatchedAt
+		}
+	}
+
+	seasons := map[int]map[int][]Episode{}
+	for key, episode := range episodes {
+		if seasons[key.show] == nil {
+			seasons[key.show] = map[int][]Episode{}
+		}
+		seasons[key.show][key.season] = append(seasons[key.show][key.season], *episode)
+	}
+
+	watchedShows := make([]WatchedShow, 0, len(showOrder))
+	for _, showID := range showOrder {
+		watchedShow := shows[showID]
+		for number, seasonEpisodes := range seasons[showID] {
+			sort.Slice(seasonEpisodes, func(i, j int) bool {
+				return seasonEpisodes[i].Number < seasonEpisodes[j].Number
+			})
+			watchedShow.Seasons = append(watchedShow.Seasons, Season{Number: number, Episodes: seasonEpisodes})
+		}
+		sort.Slice(watchedShow.Seasons, func(i, j int) bool {
+			return watchedShow.Seasons[i].Number < watchedShow.Seasons[j].Number
+		})
+		watchedShows = append(watchedShows, *watchedShow)
+	}
+
+	return watchedShows
+}
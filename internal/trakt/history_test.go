@@ -0,0 +1,141 @@
+package trakt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// historyPlay is a play in the watch history served by historyServer
+type historyPlay struct {
+	id        int
+	action    string
+	episode   int
+	watchedAt time.Time
+}
+
+// historyServer is a stand-in for the Trakt API that serves a watch history that can be changed
+type historyServer struct {
+	mu        sync.Mutex
+	watchedAt time.Time
+	plays     []historyPlay
+}
+
+func (server *historyServer) set(watchedAt time.Time, plays ...historyPlay) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.watchedAt = watchedAt
+	server.plays = plays
+}
+
+func (server *historyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/sync/last_activities":
+		fmt.Fprintf(w, `{"episodes": {"watched_at": %q}}`, server.watchedAt.Format(time.RFC3339))
+	case "/users/me/history/episodes":
+		startAt, _ := time.Parse(time.RFC3339, r.URL.Query().Get("start_at"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		var items []string
+		for _, play := range server.plays {
+			if play.watchedAt.Before(startAt) {
+				continue
+			}
+			items = append(items, fmt.Sprintf(`{"id": %v, "watched_at": %q, "action": %q, "episode": {"season": 1, "number": %v}, "show": {"title": "Show", "ids": {"trakt": 1}}}`,
+				play.id, play.watchedAt.Format(time.RFC3339), play.action, play.episode))
+		}
+		w.Header().Set("X-Pagination-Page-Count", "1")
+		w.Header().Set("X-Pagination-Item-Count", strconv.Itoa(len(items)))
+		if limit < len(items) {
+			items = items[:limit]
+		}
+		fmt.Fprintf(w, "[%v]", strings.Join(items, ","))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func startHistoryServer(t *testing.T) *historyServer {
+	t.Helper()
+	history := &historyServer{}
+	server := httptest.NewServer(history)
+	t.Cleanup(server.Close)
+
+	previousURL := apiURL
+	apiURL = server.URL
+	t.Cleanup(func() { apiURL = previousURL })
+	return history
+}
+
+func TestGetWatchedShowsFromHistoryResyncs(t *testing.T) {
+	history := startHistoryServer(t)
+	api := API{DataPath: t.TempDir()}
+	day := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	history.set(day, historyPlay{1, ActionScrobble, 1, day}, historyPlay{2, ActionScrobble, 2, day})
+	user := &User{Name: "me", CacheMaxAge: 72 * time.Hour}
+	if err := user.GetWatchedShowsFromHistory(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+
+	// Episode 1 is removed from the history
+	history.set(day.Add(time.Hour), historyPlay{2, ActionScrobble, 2, day})
+	user = &User{Name: "me", CacheMaxAge: 72 * time.Hour}
+	if err := user.GetWatchedShowsFromHistory(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	if user.FindWatchedShowByName("Show").FindSeason(1).FindEpisode(1) != nil {
+		t.Error("episode 1 was removed from the history, want it not to be watched")
+	}
+
+	// Episode 3 is added with a time before the latest play
+	history.set(day.Add(2*time.Hour), historyPlay{2, ActionScrobble, 2, day}, historyPlay{3, ActionWatch, 3, day.Add(-time.Hour)})
+	user = &User{Name: "me", CacheMaxAge: 72 * time.Hour}
+	if err := user.GetWatchedShowsFromHistory(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	if user.FindWatchedShowByName("Show").FindSeason(1).FindEpisode(3) == nil {
+		t.Error("episode 3 was added to the history, want it to be watched")
+	}
+
+	// Removing and adding a play leaves the number of plays unchanged, which is caught by the
+	// full sync once the cache is older than CacheMaxAge
+	history.set(day.Add(3*time.Hour), historyPlay{2, ActionScrobble, 2, day}, historyPlay{4, ActionWatch, 4, day.Add(-time.Hour)})
+	user = &User{Name: "me"}
+	if err := user.GetWatchedShowsFromHistory(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	season := user.FindWatchedShowByName("Show").FindSeason(1)
+	if season.FindEpisode(3) != nil || season.FindEpisode(4) == nil {
+		t.Errorf("watched episodes = %+v, want the complete history to be downloaded again", season.Episodes)
+	}
+}
+
+func TestWatchedShowsFromHistoryIgnoresCheckins(t *testing.T) {
+	day := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	items := []HistoryItem{
+		{ID: 1, WatchedAt: day, Action: ActionCheckin},
+		{ID: 2, WatchedAt: day, Action: ActionScrobble},
+	}
+	items[0].Episode.Season, items[0].Episode.Number = 1, 1
+	items[1].Episode.Season, items[1].Episode.Number = 1, 2
+
+	user := &User{}
+	if watchedShows := user.watchedShowsFromHistory(items); len(watchedShows[0].Seasons[0].Episodes) != 2 {
+		t.Errorf("watched episodes = %+v, want check-ins to count", watchedShows[0].Seasons[0].Episodes)
+	}
+
+	user.IgnoreCheckins = true
+	watchedShows := user.watchedShowsFromHistory(items)
+	if episodes := watchedShows[0].Seasons[0].Episodes; len(episodes) != 1 || episodes[0].Number != 2 {
+		t.Errorf("watched episodes = %+v, want check-ins to be ignored", episodes)
+	}
+}
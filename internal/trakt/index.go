@@ -0,0 +1,202 @@
+package trakt
+
+import (
+	"strings"
+)
+
+// watchedShowIndex contains lookup tables for the watched shows of a user.
+// An index is never modified after it has been built, so it can safely be read concurrently.
+type watchedShowIndex struct {
+	// byTitle contains the shows by their lowercase title, byName by their normalized title.
+	// Titles can be shared by several shows, such as remakes.
+	byTitle map[string][]*WatchedShow
+	byName  map[string][]*WatchedShow
+	byTrakt map[int]*WatchedShow
+	bySlug  map[string]*WatchedShow
+	byTVDB  map[int]*WatchedShow
+	byIMDB  map[string]*WatchedShow
+	byTMDB  map[int]*WatchedShow
+}
+
+func newWatchedShowIndex(watchedShows []WatchedShow) *watchedShowIndex {
+	index := &watchedShowIndex{
+		byTitle: make(map[string][]*WatchedShow, len(watchedShows)),
+		byName:  make(map[string][]*WatchedShow, len(watchedShows)),
+		byTrakt: make(map[int]*WatchedShow, len(watchedShows)),
+		bySlug:  make(map[string]*WatchedShow, len(watchedShows)),
+		byTVDB:  make(map[int]*WatchedShow, len(watchedShows)),
+		byIMDB:  make(map[string]*WatchedShow, len(watchedShows)),
+		byTMDB:  make(map[int]*WatchedShow, len(watchedShows)),
+	}
+
+	for i := range watchedShows {
+		watchedShow := &watchedShows[i]
+		watchedShow.buildSeasonIndex()
+
+		ids := watchedShow.Show.IDS
+		addToListIndex(index.byTitle, strings.ToLower(strings.TrimSpace(watchedShow.Show.Title)), watchedShow)
+		addToListIndex(index.byName, normalizeTitle(watchedShow.Show.Title), watchedShow)
+		addToIndex(index.byTrakt, ids.Trakt, watchedShow)
+		addToIndex(index.bySlug, strings.ToLower(ids.Slug), watchedShow)
+		addToIndex(index.byTVDB, ids.TVDB, watchedShow)
+		addToIndex(index.byIMDB, strings.ToLower(ids.IMDB), watchedShow)
+		addToIndex(index.byTMDB, ids.TMDB, watchedShow)
+	}
+
+	return index
+}
+
+// addToIndex adds a show to an index, keeping the first show when keys collide
+func addToIndex[K comparable](index map[K]*WatchedShow, key K, watchedShow *WatchedShow) {
+	var zero K
+	if key == zero {
+		return
+	}
+	if _, ok := index[key]; ok {
+		return
+	}
+	index[key] = watchedShow
+}
+
+// addToListIndex adds a show to an index that keeps all shows with the same key
+func addToListIndex(index map[string][]*WatchedShow, key string, watchedShow *WatchedShow) {
+	if key == "" {
+		return
+	}
+	index[key] = append(index[key], watchedShow)
+}
+
+// matchByYear returns the candidates that match year, which is 0 when it is unknown.
+// Shows whose year is known and differs from year never match.
+func matchByYear(candidates []*WatchedShow, year int) []*WatchedShow {
+	if year == 0 {
+		return candidates
+	}
+
+	var sameYear, unknownYear []*WatchedShow
+	for _, candidate := range candidates {
+		switch candidate.Show.Year {
+		case year:
+			sameYear = append(sameYear, candidate)
+		case 0:
+			unknownYear = append(unknownYear, candidate)
+		}
+	}
+	if len(sameYear) > 0 {
+		return sameYear
+	}
+	return unknownYear
+}
+
+// findByName returns the show that matches name, see FindWatchedShowByName. ambiguous indicates
+// that no show was returned because more than one show matches.
+func (index *watchedShowIndex) findByName(name string) (watchedShow *WatchedShow, ambiguous bool) {
+	year := titleYear(name)
+	for _, candidates := range [][]*WatchedShow{
+		index.byTitle[strings.ToLower(strings.TrimSpace(name))],
+		index.byName[normalizeTitle(name)],
+	} {
+		switch matches := matchByYear(candidates, year); len(matches) {
+		case 0:
+		case 1:
+			return matches[0], false
+		default:
+			ambiguous = true
+		}
+	}
+	return nil, ambiguous
+}
+
+func (w *WatchedShow) buildSeasonIndex() {
+	w.seasonIndex = make(map[int]*Season, len(w.Seasons))
+	for i := range w.Seasons {
+		season := &w.Seasons[i]
+		season.episodeIndex = make(map[int]*Episode, len(season.Episodes))
+		for j := range season.Episodes {
+			season.episodeIndex[season.Episodes[j].Number] = &season.Episodes[j]
+		}
+		w.seasonIndex[season.Number] = season
+	}
+}
+
+func (user *User) setWatchedShows(watchedShows []WatchedShow, fromCache bool) {
+	user.WatchedShows = watchedShows
+	user.FromCache = fromCache
+	user.Offline = false
+	user.index.Store(newWatchedShowIndex(user.WatchedShows))
+}
+
+func (user *User) getIndex() *watchedShowIndex {
+	index := user.index.Load()
+	if index == nil {
+		index = newWatchedShowIndex(user.WatchedShows)
+		user.index.Store(index)
+	}
+	return index
+}
+
+// FindWatchedShowByName returns a watched show for this user by name. The exact title is tried
+// before the normalized title, and a release year in name, such as "Doctor Who (2005)", is used
+// to tell shows with the same title apart. When more than one show matches, none is returned.
+func (user *User) FindWatchedShowByName(name string) *WatchedShow {
+	watchedShow, _ := user.getIndex().findByName(name)
+	return watchedShow
+}
+
+// FindWatchedShowByTraktID returns a watched show for this user by Trakt id
+func (user *User) FindWatchedShowByTraktID(traktid int) *WatchedShow {
+	return user.getIndex().byTrakt[traktid]
+}
+
+// FindWatchedShowBySlug returns a watched show for this user by Trakt slug
+func (user *User) FindWatchedShowBySlug(slug string) *WatchedShow {
+	return user.getIndex().bySlug[strings.ToLower(slug)]
+}
+
+// FindWatchedShowByTVDBID returns a watched show for this user by TVDB id
+func (user *User) FindWatchedShowByTVDBID(tvdbid int) *WatchedShow {
+	return user.getIndex().byTVDB[tvdbid]
+}
+
+// FindWatchedShowByIMDBID returns a watched show for this user by IMDb id
+func (user *User) FindWatchedShowByIMDBID(imdbid string) *WatchedShow {
+	return user.getIndex().byIMDB[strings.ToLower(imdbid)]
+}
+
+// FindWatchedShowByTMDBID returns a watched show for this user by TMDB id
+func (user *User) FindWatchedShowByTMDBID(tmdbid int) *WatchedShow {
+	return user.getIndex().byTMDB[tmdbid]
+}
+
+// FindWatchedShowBySimilarName returns the watched show for this user whose normalized title is the most
+// similar to name, as long as the similarity is at least threshold (between 0 and 1). Shows whose year
+// differs from a release year in name never match, and none is returned when several shows are the most similar.
+func (user *User) FindWatchedShowBySimilarName(name string, threshold float64) *WatchedShow {
+	normalizedName := normalizeTitle(name)
+	year := titleYear(name)
+	var bestMatch *WatchedShow
+	bestSimilarity := threshold
+	tied := false
+
+	for i := range user.WatchedShows {
+		watchedShow := &user.WatchedShows[i]
+		if year != 0 && watchedShow.Show.Year != 0 && watchedShow.Show.Year != year {
+			continue
+		}
+		similarity := titleSimilarity(normalizedName, normalizeTitle(watchedShow.Show.Title))
+		switch {
+		case similarity < bestSimilarity:
+		case bestMatch == nil || similarity > bestSimilarity:
+			bestMatch = watchedShow
+			bestSimilarity = similarity
+			tied = false
+		default:
+			tied = true
+		}
+	}
+
+	if tied {
+		return nil
+	}
+	return bestMatch
+}
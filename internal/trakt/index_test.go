@@ -0,0 +1,208 @@
+package trakt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	benchmarkShows    = 500
+	benchmarkSeasons  = 10
+	benchmarkEpisodes = 20
+)
+
+func newBenchmarkWatchedShows() []WatchedShow {
+	watchedShows := make([]WatchedShow, benchmarkShows)
+	for i := range watchedShows {
+		watchedShows[i].Show.Title = fmt.Sprintf("Show %d", i)
+		watchedShows[i].Show.IDS.Trakt = i + 1
+		watchedShows[i].Show.IDS.TVDB = 100000 + i
+		watchedShows[i].Show.IDS.IMDB = fmt.Sprintf("tt%07d", i)
+		for s := 1; s <= benchmarkSeasons; s++ {
+			season := Season{Number: s}
+			for e := 1; e <= benchmarkEpisodes; e++ {
+				season.Episodes = append(season.Episodes, Episode{Number: e})
+			}
+			watchedShows[i].Seasons = append(watchedShows[i].Seasons, season)
+		}
+	}
+	return watchedShows
+}
+
+// linearFindWatchedShowByName is the lookup that was used before the watched shows were indexed
+func linearFindWatchedShowByName(watchedShows []WatchedShow, name string) *WatchedShow {
+	for i := range watchedShows {
+		if strings.EqualFold(watchedShows[i].Show.Title, name) {
+			return &watchedShows[i]
+		}
+	}
+	return nil
+}
+
+func linearFindEpisode(watchedShow *WatchedShow, seasonNumber int, episodeNumber int) *Episode {
+	for i := range watchedShow.Seasons {
+		season := &watchedShow.Seasons[i]
+		if season.Number != seasonNumber {
+			continue
+		}
+		for j := range season.Episodes {
+			if season.Episodes[j].Number == episodeNumber {
+				return &season.Episodes[j]
+			}
+		}
+	}
+	return nil
+}
+
+func TestIndexedLookupsMatchLinearLookups(t *testing.T) {
+	user := User{}
+	user.setWatchedShows(newBenchmarkWatchedShows(), false)
+
+	for _, name := range []string{"Show 0", "show 250", "SHOW 499", "Show 500"} {
+		indexed := user.FindWatchedShowByName(name)
+		linear := linearFindWatchedShowByName(user.WatchedShows, name)
+		if indexed != linear {
+			t.Errorf("FindWatchedShowByName(%q) = %v, want %v", name, indexed, linear)
+		}
+	}
+
+	watchedShow := user.FindWatchedShowByTVDBID(100042)
+	if watchedShow == nil || watchedShow.Show.IDS.Trakt != 43 {
+		t.Fatalf("FindWatchedShowByTVDBID(100042) = %v, want show with Trakt id 43", watchedShow)
+	}
+
+	if got := user.FindWatchedShowByIMDBID("TT0000042"); got != watchedShow {
+		t.Errorf("FindWatchedShowByIMDBID(TT0000042) = %v, want %v", got, watchedShow)
+	}
+
+	season := watchedShow.FindSeason(3)
+	if season == nil {
+		t.Fatal("FindSeason(3) = nil")
+	}
+	if got, want := season.FindEpisode(7), linearFindEpisode(watchedShow, 3, 7); got != want {
+		t.Errorf("FindEpisode(7) = %v, want %v", got, want)
+	}
+	if got := season.FindEpisode(benchmarkEpisodes + 1); got != nil {
+		t.Errorf("FindEpisode(%d) = %v, want nil", benchmarkEpisodes+1, got)
+	}
+}
+
+func BenchmarkFindEpisodeLinear(b *testing.B) {
+	watchedShows := newBenchmarkWatchedShows()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		watchedShow := linearFindWatchedShowByName(watchedShows, fmt.Sprintf("show %d", i%benchmarkShows))
+		if linearFindEpisode(watchedShow, benchmarkSeasons, benchmarkEpisodes) == nil {
+			b.Fatal("episode not found")
+		}
+	}
+}
+
+func BenchmarkFindEpisodeIndexed(b *testing.B) {
+	user := User{}
+	user.setWatchedShows(newBenchmarkWatchedShows(), false)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		watchedShow := user.FindWatchedShowByName(fmt.Sprintf("show %d", i%benchmarkShows))
+		if watchedShow.FindSeason(benchmarkSeasons).FindEpisode(benchmarkEpisodes) == nil {
+			b.Fatal("episode not found")
+		}
+	}
+}
+
+func BenchmarkFindEpisodeIndexedParallel(b *testing.B) {
+	user := User{}
+	user.setWatchedShows(newBenchmarkWatchedShows(), false)
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			watchedShow := user.FindWatchedShowByName(fmt.Sprintf("show %d", i%benchmarkShows))
+			if watchedShow.FindSeason(benchmarkSeasons).FindEpisode(benchmarkEpisodes) == nil {
+				b.Fatal("episode not found")
+			}
+			i++
+		}
+	})
+}
+
+func newWatchedShow(title string, year, traktID int) WatchedShow {
+	watchedShow := WatchedShow{}
+	watchedShow.Show.Title = title
+	watchedShow.Show.Year = year
+	watchedShow.Show.IDS.Trakt = traktID
+	return watchedShow
+}
+
+func TestFindWatchedShowByNameWithSharedTitles(t *testing.T) {
+	user := User{}
+	user.setWatchedShows([]WatchedShow{
+		newWatchedShow("Shameless", 2004, 1),
+		newWatchedShow("Shameless", 2011, 2),
+		newWatchedShow("The Flash", 2014, 3),
+		newWatchedShow("Flash", 0, 4),
+		newWatchedShow("Doctor Who", 1963, 5),
+	}, false)
+
+	tests := []struct {
+		name    string
+		traktID int
+	}{
+		// The release year tells shows with the same title apart
+		{"Shameless (2011)", 2},
+		{"Shameless 2004", 1},
+		{"Shameless", 0},
+		{"Shameless (2020)", 0},
+		// The exact title is tried before the normalized title
+		{"The Flash", 3},
+		{"flash", 4},
+		{"Flash!", 0},
+		// A show with a different year does not match
+		{"Doctor Who (2005)", 0},
+		{"Doctor Who", 5},
+	}
+	for _, test := range tests {
+		got := user.FindWatchedShowByName(test.name)
+		switch {
+		case test.traktID == 0 && got != nil:
+			t.Errorf("FindWatchedShowByName(%q) = %v, want no match", test.name, got.Show.IDS.Trakt)
+		case test.traktID != 0 && (got == nil || got.Show.IDS.Trakt != test.traktID):
+			t.Errorf("FindWatchedShowByName(%q) = %v, want show %d", test.name, got, test.traktID)
+		}
+	}
+}
+
+func TestFindWatchedShowBySimilarNameWithSharedTitles(t *testing.T) {
+	user := User{}
+	user.setWatchedShows([]WatchedShow{
+		newWatchedShow("Doctor Who", 1963, 1),
+		newWatchedShow("Doctor Who", 2005, 2),
+		newWatchedShow("Shameless", 2011, 3),
+	}, false)
+
+	tests := []struct {
+		name    string
+		traktID int
+	}{
+		// Shows that are equally similar are ambiguous
+		{"Doctor Whoo", 0},
+		// The release year tells them apart
+		{"Doctor Whoo (2005)", 2},
+		// A show with a different year does not match
+		{"Shameles (2004)", 0},
+		{"Shameles (2011)", 3},
+	}
+	for _, test := range tests {
+		got := user.FindWatchedShowBySimilarName(test.name, 0.8)
+		switch {
+		case test.traktID == 0 && got != nil:
+			t.Errorf("FindWatchedShowBySimilarName(%q) = %v, want no match", test.name, got.Show.IDS.Trakt)
+		case test.traktID != 0 && (got == nil || got.Show.IDS.Trakt != test.traktID):
+			t.Errorf("FindWatchedShowBySimilarName(%q) = %v, want show %d", test.name, got, test.traktID)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package trakt
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	titleYearRegex         = regexp.MustCompile(`\(\s*(\d{4})\s*\)`)
+	titleTrailingYearRegex = regexp.MustCompile(`^(.+)\s+(\d{4})$`)
+	titleArticles          = []string{"the", "a", "an"}
+)
+
+// normalizeTitle reduces a show title to a form that ignores the differences commonly
+// found between folder names and Trakt titles: casing, diacritics, punctuation, "&" vs "and",
+// release years and leading articles.
+func normalizeTitle(title string) string {
+	title = removeDiacritics(title)
+	title = strings.ToLower(title)
+	title = strings.ReplaceAll(title, "&", " and ")
+	title = titleYearRegex.ReplaceAllString(title, " ")
+
+	title = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\'' || r == '’' || r == '.':
+			return -1
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			return r
+		default:
+			return ' '
+		}
+	}, title)
+
+	words := strings.Fields(title)
+	if len(words) > 1 {
+		for _, article := range titleArticles {
+			if words[0] == article {
+				words = words[1:]
+				break
+			}
+		}
+	}
+	title = strings.Join(words, " ")
+
+	return titleTrailingYearRegex.ReplaceAllString(title, "$1")
+}
+
+// titleYear returns the release year in a title such as "Doctor Who (2005)" or "Doctor Who 2005",
+// or 0 when it does not contain one
+func titleYear(title string) int {
+	match := titleYearRegex.FindStringSubmatch(title)
+	if match == nil {
+		match = titleTrailingYearRegex.FindStringSubmatch(strings.TrimSpace(title))
+	}
+	if match == nil {
+		return 0
+	}
+	year, _ := strconv.Atoi(match[len(match)-1])
+	return year
+}
+
+func removeDiacritics(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		return r
+	}, norm.NFD.String(s))
+}
+
+// titleSimilarity returns how similar two normalized titles are as a value between 0 and 1,
+// based on the Levenshtein distance between them
+func titleSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = lo.Min([]int{previous[j] + 1, current[j-1] + 1, previous[j-1] + cost})
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
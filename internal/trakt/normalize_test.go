@@ -0,0 +1,47 @@
+package trakt
+
+import "testing"
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Grey's Anatomy", "greys anatomy"},
+		{"Greys Anatomy", "greys anatomy"},
+		{"Marvel's Agents of S.H.I.E.L.D.", "marvels agents of shield"},
+		{"Law & Order", "law and order"},
+		{"Doctor Who (2005)", "doctor who"},
+		{"Doctor Who 2005", "doctor who"},
+		{"The Office (US)", "office us"},
+		{"Pokémon", "pokemon"},
+		{"1923", "1923"},
+		{"The", "the"},
+	}
+
+	for _, test := range tests {
+		if got := normalizeTitle(test.title); got != test.want {
+			t.Errorf("normalizeTitle(%q) = %q, want %q", test.title, got, test.want)
+		}
+	}
+}
+
+func TestFindWatchedShowBySimilarName(t *testing.T) {
+	user := User{}
+	user.setWatchedShows([]WatchedShow{
+		{Show: show{Title: "Brooklyn Nine-Nine"}},
+		{Show: show{Title: "Battlestar Galactica"}},
+	}, false)
+
+	if got := user.FindWatchedShowByName("brooklyn nine nine"); got == nil || got.Show.Title != "Brooklyn Nine-Nine" {
+		t.Errorf("FindWatchedShowByName(brooklyn nine nine) = %v, want Brooklyn Nine-Nine", got)
+	}
+
+	if got := user.FindWatchedShowBySimilarName("Brooklyn 99", 0.8); got != nil {
+		t.Errorf("FindWatchedShowBySimilarName(Brooklyn 99, 0.8) = %v, want nil", got)
+	}
+
+	if got := user.FindWatchedShowBySimilarName("Battlestar Galactika", 0.9); got == nil || got.Show.Title != "Battlestar Galactica" {
+		t.Errorf("FindWatchedShowBySimilarName(Battlestar Galactika, 0.9) = %v, want Battlestar Galactica", got)
+	}
+}
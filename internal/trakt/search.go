@@ -0,0 +1,236 @@
+package trakt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/helpers"
+)
+
+const (
+	// searchLimit is the number of search results whose title is compared with the name of a show
+	searchLimit = 10
+	// searchAliasLimit is the number of search results whose aliases are compared as well
+	searchAliasLimit = 3
+	// unresolvedTTL is how long a show that could not be found is not searched again
+	unresolvedTTL = 24 * time.Hour
+)
+
+type searchResult struct {
+	Type  string  `json:"type"`
+	Score float64 `json:"score"`
+	Show  show    `json:"show"`
+}
+
+type showAlias struct {
+	Title   string `json:"title"`
+	Country string `json:"country"`
+}
+
+// ShowResolver resolves show names to Trakt ids using the Trakt search.
+// Resolved ids are cached in the API data path so subsequent runs can match them exactly.
+// Names that could not be resolved are cached as well, and searched again after unresolvedTTL.
+type ShowResolver struct {
+	api            API
+	path           string
+	unresolvedPath string
+
+	mutex      sync.Mutex
+	ids        map[string]int
+	unresolved map[string]time.Time
+	// searches are the searches in progress by key, so a name is only searched once at a time
+	searches map[string]*search
+}
+
+// search is a search for a show name that is in progress
+type search struct {
+	done chan struct{}
+	id   int
+	err  error
+}
+
+// NewShowResolver creates a new ShowResolver instance
+func NewShowResolver(api API) (*ShowResolver, error) {
+	err := api.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := &ShowResolver{
+		api:            api,
+		path:           api.DataPath + "/resolved_shows.json",
+		unresolvedPath: api.DataPath + "/unresolved_shows.json",
+		ids:            map[string]int{},
+		unresolved:     map[string]time.Time{},
+		searches:       map[string]*search{},
+	}
+
+	if err := readJSONFile(resolver.path, &resolver.ids); err != nil {
+		return nil, err
+	}
+	if err := readJSONFile(resolver.unresolvedPath, &resolver.unresolved); err != nil {
+		return nil, err
+	}
+	for key, searchedAt := range resolver.unresolved {
+		if time.Since(searchedAt) >= unresolvedTTL {
+			delete(resolver.unresolved, key)
+		}
+	}
+
+	return resolver, nil
+}
+
+func readJSONFile(path string, value interface{}) error {
+	if !helpers.FileExists(path) {
+		return nil
+	}
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(file, value)
+}
+
+func writeJSONFile(path string, value interface{}) error {
+	jsonString, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, jsonString, 0o600)
+}
+
+// resolveKey returns the key of name in the cache. The release year is part of the key, so
+// remakes with the same title are resolved separately.
+func resolveKey(name string) string {
+	key := normalizeTitle(name)
+	if year := titleYear(name); year != 0 {
+		key += fmt.Sprintf(" (%d)", year)
+	}
+	return key
+}
+
+// Resolve returns the Trakt id of the show matching name, or 0 if no show could be found
+func (resolver *ShowResolver) Resolve(ctx context.Context, name string) (int, error) {
+	key := resolveKey(name)
+
+	resolver.mutex.Lock()
+	if id, ok := resolver.ids[key]; ok {
+		resolver.mutex.Unlock()
+		return id, nil
+	}
+	if searchedAt, ok := resolver.unresolved[key]; ok && time.Since(searchedAt) < unresolvedTTL {
+		resolver.mutex.Unlock()
+		return 0, nil
+	}
+	if current, ok := resolver.searches[key]; ok {
+		resolver.mutex.Unlock()
+		select {
+		case <-current.done:
+			return current.id, current.err
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	current := &search{done: make(chan struct{})}
+	resolver.searches[key] = current
+	resolver.mutex.Unlock()
+
+	// The request is sent without holding the lock, so other shows can be resolved meanwhile
+	current.id, current.err = searchShow(ctx, resolver.api, name)
+
+	resolver.mutex.Lock()
+	delete(resolver.searches, key)
+	if current.err == nil {
+		if current.id != 0 {
+			resolver.ids[key] = current.id
+			current.err = writeJSONFile(resolver.path, resolver.ids)
+		} else {
+			resolver.unresolved[key] = time.Now()
+			current.err = writeJSONFile(resolver.unresolvedPath, resolver.unresolved)
+		}
+	}
+	resolver.mutex.Unlock()
+	close(current.done)
+
+	return current.id, current.err
+}
+
+// searchShow returns the Trakt id of the show whose title or one of its aliases matches name,
+// or 0 if none of the search results does. A release year in name must match the year of the show.
+func searchShow(ctx context.Context, api API, name string) (int, error) {
+	query := url.Values{}
+	query.Set("query", name)
+	query.Set("fields", "title,aliases")
+	query.Set("limit", strconv.Itoa(searchLimit))
+
+	result, err := api.sendRequest(ctx, http.MethodGet, fmt.Sprintf("/search/show?%v", query.Encode()), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := result.checkStatus(); err != nil {
+		return 0, err
+	}
+
+	var results []searchResult
+	err = json.Unmarshal(result.Body, &results)
+	if err != nil {
+		return 0, err
+	}
+
+	normalizedName := normalizeTitle(name)
+	year := titleYear(name)
+	var candidates []show
+	for _, result := range results {
+		if result.Show.IDS.Trakt == 0 || (year != 0 && result.Show.Year != 0 && result.Show.Year != year) {
+			continue
+		}
+		if normalizeTitle(result.Show.Title) == normalizedName {
+			return result.Show.IDS.Trakt, nil
+		}
+		candidates = append(candidates, result.Show)
+	}
+
+	// The search also matches aliases, such as titles in other countries, but does not return them
+	for i, candidate := range candidates {
+		if i == searchAliasLimit {
+			break
+		}
+		aliases, err := getShowAliases(ctx, api, candidate.IDS.Trakt)
+		if err != nil {
+			return 0, err
+		}
+		for _, alias := range aliases {
+			if normalizeTitle(alias.Title) == normalizedName {
+				return candidate.IDS.Trakt, nil
+			}
+		}
+	}
+
+	return 0, nil
+}
+
+func getShowAliases(ctx context.Context, api API, traktID int) ([]showAlias, error) {
+	result, err := api.sendRequest(ctx, http.MethodGet, fmt.Sprintf("/shows/%d/aliases", traktID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := result.checkStatus(); err != nil {
+		return nil, err
+	}
+
+	var aliases []showAlias
+	err = json.Unmarshal(result.Body, &aliases)
+	if err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
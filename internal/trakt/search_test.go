@@ -0,0 +1,116 @@
+package trakt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// startSearchServer starts a stand-in for the Trakt API that answers show searches and alias
+// requests, and counts the searches
+func startSearchServer(t *testing.T) *int32 {
+	t.Helper()
+	var searches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search/show":
+			atomic.AddInt32(&searches, 1)
+			switch r.URL.Query().Get("query") {
+			case "Shameless (2011)", "Shameless":
+				fmt.Fprint(w, `[
+					{"type": "show", "score": 100, "show": {"title": "Shameless", "year": 2004, "ids": {"trakt": 1}}},
+					{"type": "show", "score": 90, "show": {"title": "Shameless", "year": 2011, "ids": {"trakt": 2}}}
+				]`)
+			case "Money Heist":
+				fmt.Fprint(w, `[
+					{"type": "show", "score": 100, "show": {"title": "Money", "year": 2010, "ids": {"trakt": 3}}},
+					{"type": "show", "score": 90, "show": {"title": "La casa de papel", "year": 2017, "ids": {"trakt": 4}}}
+				]`)
+			default:
+				fmt.Fprint(w, `[{"type": "show", "score": 10, "show": {"title": "Something Else", "year": 2000, "ids": {"trakt": 5}}}]`)
+			}
+		case "/shows/4/aliases":
+			fmt.Fprint(w, `[{"title": "Money Heist", "country": "us"}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	previousURL := apiURL
+	apiURL = server.URL
+	t.Cleanup(func() { apiURL = previousURL })
+	return &searches
+}
+
+func TestResolve(t *testing.T) {
+	startSearchServer(t)
+	resolver, err := NewShowResolver(API{DataPath: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		// The release year is used to pick the right show, not the first result
+		{"Shameless (2011)", 2},
+		{"Shameless", 1},
+		// An alias that matches the name
+		{"Money Heist", 4},
+		// A result that does not match the name is not used
+		{"Unknown Show", 0},
+	}
+	for _, test := range tests {
+		if got, err := resolver.Resolve(context.Background(), test.name); err != nil || got != test.want {
+			t.Errorf("Resolve(%q) = %v, %v, want %v", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestResolveCachesResults(t *testing.T) {
+	searches := startSearchServer(t)
+	dataPath := t.TempDir()
+	resolver, err := NewShowResolver(API{DataPath: dataPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent lookups of the same name only search once
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := resolver.Resolve(context.Background(), "Unknown Show"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := resolver.Resolve(context.Background(), "Shameless (2011)"); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(searches); got != 2 {
+		t.Errorf("searches = %d, want 2", got)
+	}
+
+	// Both resolved and unresolved shows are remembered by a new resolver
+	resolver, err = NewShowResolver(API{DataPath: dataPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Unknown Show", "Shameless (2011)"} {
+		if _, err := resolver.Resolve(context.Background(), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(searches); got != 2 {
+		t.Errorf("searches after reading the cache = %d, want 2", got)
+	}
+}
@@ -1,15 +1,18 @@
 package trakt
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"sync/atomic"
 	"time"
 )
 
 type show struct {
 	Title string `json:"title"`
+	Year  int    `json:"year,omitempty"`
 	IDS   struct {
 		Trakt int
 		Slug  string
@@ -23,17 +26,19 @@ type show struct {
 type Season struct {
 	Number   int       `json:"number"`
 	Episodes []Episode `json:"episodes"`
+
+	episodeIndex map[int]*Episode
 }
 
 // FindEpisode tries to find if a (partially) watched season on Trakt contains an episode with a specified number
 func (s *Season) FindEpisode(episodeNumber int) *Episode {
-	if len(s.Episodes) == 0 {
-		return nil
+	if s.episodeIndex != nil {
+		return s.episodeIndex[episodeNumber]
 	}
 
-	for _, episode := range s.Episodes {
-		if episode.Number == episodeNumber {
-			return &episode
+	for i := range s.Episodes {
+		if s.Episodes[i].Number == episodeNumber {
+			return &s.Episodes[i]
 		}
 	}
 
@@ -42,8 +47,12 @@ func (s *Season) FindEpisode(episodeNumber int) *Episode {
 
 // Episode represents an episode that was reported as watched on Trakt
 type Episode struct {
-	Number      int       `json:"number"`
-	LastWatched time.Time `json:"last_watched_at"`
+	Number       int       `json:"number"`
+	Plays        int       `json:"plays"`
+	FirstWatched time.Time `json:"first_watched_at,omitempty"`
+	LastWatched  time.Time `json:"last_watched_at"`
+	// WatchedBy describes who reported the last play when it was not reported by Trakt
+	WatchedBy string `json:"-"`
 }
 
 // LastWatchedBefore returns when an episode was reported as last watched on Trakt
@@ -51,21 +60,29 @@ func (e *Episode) LastWatchedBefore(t time.Time) bool {
 	return e.LastWatched.Sub(t) < 0
 }
 
+// FirstWatchedBefore returns when an episode was reported as first watched on Trakt.
+// The first watched time is only known when the watched state was built from the watch history.
+func (e *Episode) FirstWatchedBefore(t time.Time) bool {
+	return !e.FirstWatched.IsZero() && e.FirstWatched.Sub(t) < 0
+}
+
 // WatchedShow represents a show that reported watched on Trakt
 type WatchedShow struct {
 	Show    show     `json:"show"`
 	Seasons []Season `json:"seasons"`
+
+	seasonIndex map[int]*Season
 }
 
 // FindSeason tries to find if a watched show on Trakt contains a season with a specified number
 func (w *WatchedShow) FindSeason(seasonNumber int) *Season {
-	if len(w.Seasons) == 0 {
-		return nil
+	if w.seasonIndex != nil {
+		return w.seasonIndex[seasonNumber]
 	}
 
-	for _, season := range w.Seasons {
-		if season.Number == seasonNumber {
-			return &season
+	for i := range w.Seasons {
+		if w.Seasons[i].Number == seasonNumber {
+			return &w.Seasons[i]
 		}
 	}
 
@@ -76,69 +93,98 @@ func (w *WatchedShow) FindSeason(seasonNumber int) *Season {
 type User struct {
 	Name         string
 	WatchedShows []WatchedShow
+	// CacheMaxAge is how old the local watched shows cache may be before it is no longer
+	// used as a fallback when Trakt is unavailable, and how often the cached history is downloaded again
+	CacheMaxAge time.Duration
+	// IgnoreCheckins leaves check-ins out of the watched shows built from the history, as they
+	// are added when an episode starts playing
+	IgnoreCheckins bool
+	// FromCache indicates that WatchedShows was served from the local cache
+	FromCache bool
+	// Offline indicates that WatchedShows was served from the local cache because Trakt was unavailable
+	Offline bool
+
+	index atomic.Pointer[watchedShowIndex]
 }
 
-// GetWatchedShows returns the watched shows for this user
-func (user *User) GetWatchedShows(api API) error {
+// GetWatchedShows returns the watched shows for this user.
+// The watched shows are cached in the API data path and are only downloaded again when
+// Trakt reports that the watched episodes have changed since the last sync.
+func (user *User) GetWatchedShows(ctx context.Context, api API) error {
 	err := api.validate()
 	if err != nil {
 		return err
 	}
 
-	result, err := api.sendRequest(http.MethodGet, fmt.Sprintf("/users/%v/watched/shows", user.Name), nil)
-	if err != nil {
-		return err
+	cacheFile := watchedShowsCacheFile(api, user.Name)
+	cache := &watchedShowsCache{}
+	if err := cache.ReadFromFile(cacheFile); err != nil || cache.User != user.Name {
+		cache = nil
 	}
 
-	err = json.Unmarshal(result.Body, &user.WatchedShows)
+	activities, err := getLastActivities(ctx, api)
 	if err != nil {
-		return err
+		return user.useCacheAfterError(cache, err)
 	}
 
-	return nil
-}
+	if cache != nil && cache.EpisodesWatchedAt.Equal(activities.Episodes.WatchedAt) {
+		user.setWatchedShows(cache.WatchedShows, true)
+		cache.SyncedAt = time.Now()
+		return cache.WriteToFile(cacheFile)
+	}
 
-// FindWatchedShowByName returns a watched show for this user by name
-func (user *User) FindWatchedShowByName(name string) *WatchedShow {
-	if user.WatchedShows == nil {
-		return nil
+	watchedShows, err := getWatchedShows(ctx, api, user.Name)
+	if err != nil {
+		return user.useCacheAfterError(cache, err)
 	}
 
-	for _, show := range user.WatchedShows {
-		if strings.EqualFold(show.Show.Title, name) {
-			return &show
-		}
+	user.setWatchedShows(watchedShows, false)
+
+	cache = &watchedShowsCache{
+		User:              user.Name,
+		EpisodesWatchedAt: activities.Episodes.WatchedAt,
+		SyncedAt:          time.Now(),
+		WatchedShows:      watchedShows,
 	}
+	return cache.WriteToFile(cacheFile)
+}
 
-	return nil
+func (user *User) useCacheAfterError(cache *watchedShowsCache, err error) error {
+	if cache == nil {
+		return err
+	}
+	return user.useCachedShowsAfterError(cache.SyncedAt, cache.WatchedShows, err)
 }
 
-// FindWatchedShowByTVDBID returns a watched show for this user by TVDB id
-func (user *User) FindWatchedShowByTVDBID(tvdbid int) *WatchedShow {
-	if user.WatchedShows == nil {
-		return nil
+func (user *User) useCachedShowsAfterError(syncedAt time.Time, watchedShows []WatchedShow, err error) error {
+	if !errors.Is(err, ErrUnavailable) {
+		return err
 	}
 
-	for _, show := range user.WatchedShows {
-		if show.Show.IDS.TVDB == tvdbid {
-			return &show
-		}
+	if time.Since(syncedAt) > user.CacheMaxAge {
+		return fmt.Errorf("%w: cached watched shows from %v are too old to be used", err, syncedAt.Format(time.RFC3339))
 	}
 
+	user.setWatchedShows(watchedShows, true)
+	user.Offline = true
 	return nil
 }
 
-// FindWatchedShowByTVDBID returns a watched show for this user by IMDb id
-func (user *User) FindWatchedShowByIMDBID(imdbid string) *WatchedShow {
-	if user.WatchedShows == nil {
-		return nil
+func getWatchedShows(ctx context.Context, api API, userName string) ([]WatchedShow, error) {
+	result, err := api.sendRequest(ctx, http.MethodGet, fmt.Sprintf("/users/%v/watched/shows", userName), nil)
+	if err != nil {
+		return nil, err
 	}
 
-	for _, show := range user.WatchedShows {
-		if strings.EqualFold(show.Show.IDS.IMDB, imdbid) {
-			return &show
-		}
+	if err := result.checkStatus(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	var watchedShows []WatchedShow
+	err = json.Unmarshal(result.Body, &watchedShows)
+	if err != nil {
+		return nil, err
+	}
+
+	return watchedShows, nil
 }
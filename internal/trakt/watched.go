@@ -0,0 +1,79 @@
+package trakt
+
+import "time"
+
+// WatchedEpisode is an episode that was reported as watched outside of Trakt, for example by
+// a media server, and might not have been synced to Trakt yet
+type WatchedEpisode struct {
+	Show      string
+	Season    int
+	Episode   int
+	WatchedAt time.Time
+	// WatchedBy describes who reported the episode as watched
+	WatchedBy string
+}
+
+// AddWatchedEpisodes merges episodes that were watched outside of Trakt into the watched shows.
+// Shows are matched by name like FindWatchedShowByName and added when they were not watched yet.
+// An episode that was watched after it was last watched according to Trakt counts as an additional
+// play. It returns the episodes that were left out because their show matches more than one show.
+// It must not be called while the watched shows are being searched.
+func (user *User) AddWatchedEpisodes(episodes []WatchedEpisode) (ambiguous []WatchedEpisode) {
+	if len(episodes) == 0 {
+		return nil
+	}
+
+	index := user.getIndex()
+	for _, watched := range episodes {
+		watchedShow, ambiguousShow := index.findByName(watched.Show)
+		if ambiguousShow {
+			ambiguous = append(ambiguous, watched)
+			continue
+		}
+		if watchedShow == nil {
+			user.WatchedShows = append(user.WatchedShows, WatchedShow{Show: show{Title: watched.Show}})
+			// Appending can move the shows, so the index has to be rebuilt
+			index = newWatchedShowIndex(user.WatchedShows)
+			watchedShow = &user.WatchedShows[len(user.WatchedShows)-1]
+		}
+
+		var season *Season
+		for i := range watchedShow.Seasons {
+			if watchedShow.Seasons[i].Number == watched.Season {
+				season = &watchedShow.Seasons[i]
+				break
+			}
+		}
+		if season == nil {
+			watchedShow.Seasons = append(watchedShow.Seasons, Season{Number: watched.Season})
+			season = &watchedShow.Seasons[len(watchedShow.Seasons)-1]
+		}
+
+		var episode *Episode
+		for i := range season.Episodes {
+			if season.Episodes[i].Number == watched.Episode {
+				episode = &season.Episodes[i]
+				break
+			}
+		}
+		if episode == nil {
+			season.Episodes = append(season.Episodes, Episode{Number: watched.Episode, FirstWatched: watched.WatchedAt})
+			episode = &season.Episodes[len(season.Episodes)-1]
+		}
+
+		if watched.WatchedAt.After(episode.LastWatched) {
+			episode.LastWatched = watched.WatchedAt
+			episode.Plays++
+			episode.WatchedBy = watched.WatchedBy
+		}
+	}
+
+	user.index.Store(newWatchedShowIndex(user.WatchedShows))
+	return ambiguous
+}
+
+// TitlesMatch indicates if two show titles are the same, ignoring the differences commonly found
+// between folder names and the titles used by Trakt and media servers
+func TitlesMatch(a, b string) bool {
+	return normalizeTitle(a) == normalizeTitle(b)
+}
@@ -0,0 +1,75 @@
+package trakt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddWatchedEpisodes(t *testing.T) {
+	lastWatched := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	user := &User{}
+	user.setWatchedShows([]WatchedShow{{
+		Show: show{Title: "The Office (US)"},
+		Seasons: []Season{{
+			Number:   1,
+			Episodes: []Episode{{Number: 1, Plays: 1, LastWatched: lastWatched}},
+		}},
+	}}, false)
+
+	watchedAt := lastWatched.Add(24 * time.Hour)
+	user.AddWatchedEpisodes([]WatchedEpisode{
+		{Show: "the office (us)", Season: 1, Episode: 1, WatchedAt: watchedAt},
+		{Show: "The Office (US)", Season: 2, Episode: 3, WatchedAt: watchedAt},
+		{Show: "New Show", Season: 1, Episode: 1, WatchedAt: watchedAt},
+		// Already known to Trakt, so it is not an additional play
+		{Show: "The Office (US)", Season: 1, Episode: 1, WatchedAt: lastWatched},
+	})
+
+	episode := user.FindWatchedShowByName("The Office (US)").FindSeason(1).FindEpisode(1)
+	if episode.Plays != 2 || !episode.LastWatched.Equal(watchedAt) {
+		t.Errorf("rewatched episode = %+v, want 2 plays last watched at %v", episode, watchedAt)
+	}
+
+	episode = user.FindWatchedShowByName("The Office (US)").FindSeason(2).FindEpisode(3)
+	if episode == nil || episode.Plays != 1 || !episode.FirstWatched.Equal(watchedAt) {
+		t.Errorf("new episode = %+v, want a single play", episode)
+	}
+
+	if watchedShow := user.FindWatchedShowByName("New Show"); watchedShow == nil || watchedShow.FindSeason(1).FindEpisode(1) == nil {
+		t.Errorf("new show = %+v, want it to be added", watchedShow)
+	}
+}
+
+func TestAddWatchedEpisodesWithSharedTitles(t *testing.T) {
+	user := &User{}
+	user.setWatchedShows([]WatchedShow{
+		newWatchedShow("Doctor Who", 1963, 1),
+		newWatchedShow("Doctor Who", 2005, 2),
+	}, false)
+
+	watchedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	ambiguous := user.AddWatchedEpisodes([]WatchedEpisode{
+		{Show: "Doctor Who", Season: 1, Episode: 1, WatchedAt: watchedAt, WatchedBy: "plex:alice"},
+		{Show: "Doctor Who (2005)", Season: 1, Episode: 2, WatchedAt: watchedAt, WatchedBy: "plex:alice"},
+	})
+
+	if len(ambiguous) != 1 || ambiguous[0].Episode != 1 {
+		t.Errorf("ambiguous = %+v, want the episode without a year", ambiguous)
+	}
+	if len(user.WatchedShows) != 2 || user.FindWatchedShowByTraktID(1).FindSeason(1) != nil {
+		t.Errorf("watched shows = %+v, want the ambiguous episode to be left out", user.WatchedShows)
+	}
+	episode := user.FindWatchedShowByTraktID(2).FindSeason(1).FindEpisode(2)
+	if episode == nil || episode.WatchedBy != "plex:alice" {
+		t.Errorf("episode = %+v, want it to be watched by plex:alice", episode)
+	}
+}
+
+func TestTitlesMatch(t *testing.T) {
+	if !TitlesMatch("Marvel's Agents of S.H.I.E.L.D.", "marvels agents of shield") {
+		t.Error("expected titles to match")
+	}
+	if TitlesMatch("Show", "Other Show") {
+		t.Error("expected titles not to match")
+	}
+}
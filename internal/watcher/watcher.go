@@ -0,0 +1,160 @@
+// Package watcher implements recursive watching of folders for added and removed files
+package watcher
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// EventType is the kind of change to a file
+type EventType int
+
+const (
+	// Added indicates that a file was created, modified or moved into a watched folder
+	Added EventType = iota
+	// Removed indicates that a file or folder was removed or moved out of a watched folder.
+	// For a folder, this applies to everything in it.
+	Removed
+)
+
+// Event is a change to a file in a watched folder
+type Event struct {
+	Type EventType
+	Path string
+}
+
+// ErrOverflow is reported when events were lost, so the watched folders should be rescanned
+var ErrOverflow = errors.New("file system events were lost")
+
+// Watcher watches folders and all of their subfolders
+type Watcher struct {
+	watcher *fsnotify.Watcher
+	include func(path string) bool
+
+	// Events receives the changes to the files for which include returns true
+	Events chan Event
+	// Errors receives the errors that occur while watching
+	Errors chan error
+}
+
+// New creates a new Watcher instance that watches folders recursively.
+// Only changes to files for which include returns true are reported.
+func New(folders []string, include func(path string) bool) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	watcher := &Watcher{
+		watcher: fsWatcher,
+		include: include,
+		Events:  make(chan Event, 100),
+		Errors:  make(chan error, 10),
+	}
+
+	for _, folder := range folders {
+		if err := watcher.addRecursive(folder, nil); err != nil {
+			fsWatcher.Close()
+			return nil, err
+		}
+	}
+
+	return watcher, nil
+}
+
+// addRecursive watches folder and its subfolders, calling found for every file in them
+func (watcher *Watcher) addRecursive(folder string, found func(path string)) error {
+	return filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Folders that disappear while walking are reported by their own events
+			if os.IsNotExist(err) && path != folder {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() {
+			if strings.HasPrefix(entry.Name(), ".") && path != folder {
+				return filepath.SkipDir
+			}
+			return watcher.watcher.Add(path)
+		}
+		if found != nil && watcher.include(path) {
+			found(path)
+		}
+		return nil
+	})
+}
+
+// Run translates file system events until ctx is cancelled
+func (watcher *Watcher) Run(ctx context.Context) {
+	defer watcher.watcher.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err, ok := <-watcher.watcher.Errors:
+			if !ok {
+				return
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				err = ErrOverflow
+			}
+			watcher.sendError(ctx, err)
+		case event, ok := <-watcher.watcher.Events:
+			if !ok {
+				return
+			}
+			watcher.handle(ctx, event)
+		}
+	}
+}
+
+func (watcher *Watcher) handle(ctx context.Context, event fsnotify.Event) {
+	switch {
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		watcher.send(ctx, Event{Type: Removed, Path: event.Name})
+
+	case event.Has(fsnotify.Create), event.Has(fsnotify.Write):
+		info, err := os.Stat(event.Name)
+		if err != nil {
+			// The file was removed again before it could be inspected
+			return
+		}
+
+		if info.IsDir() {
+			// A folder that is moved into a watched folder already contains files
+			err := watcher.addRecursive(event.Name, func(path string) {
+				watcher.send(ctx, Event{Type: Added, Path: path})
+			})
+			if err != nil {
+				watcher.sendError(ctx, err)
+			}
+			return
+		}
+
+		if watcher.include(event.Name) {
+			watcher.send(ctx, Event{Type: Added, Path: event.Name})
+		}
+	}
+}
+
+func (watcher *Watcher) send(ctx context.Context, event Event) {
+	select {
+	case watcher.Events <- event:
+	case <-ctx.Done():
+	}
+}
+
+func (watcher *Watcher) sendError(ctx context.Context, err error) {
+	select {
+	case watcher.Errors <- err:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,128 @@
+package watcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func isVideo(path string) bool {
+	return strings.HasSuffix(path, ".mkv")
+}
+
+func startWatcher(t *testing.T, folder string) *Watcher {
+	t.Helper()
+
+	watcher, err := New([]string{folder}, isVideo)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go watcher.Run(ctx)
+	return watcher
+}
+
+// waitFor waits for an event for path with the wanted type, ignoring other events
+func waitFor(t *testing.T, watcher *Watcher, eventType EventType, path string) {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-watcher.Events:
+			if event.Type == eventType && event.Path == path {
+				return
+			}
+		case err := <-watcher.Errors:
+			t.Fatalf("unexpected error: %v", err)
+		case <-timeout:
+			t.Fatalf("timed out waiting for event %v on %v", eventType, path)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcherReportsNewFilesInSubfolders(t *testing.T) {
+	folder := t.TempDir()
+	season := filepath.Join(folder, "Show", "Season 1")
+	if err := os.MkdirAll(season, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	watcher := startWatcher(t, folder)
+
+	path := filepath.Join(season, "Show.S01E01.mkv")
+	writeFile(t, path)
+	waitFor(t, watcher, Added, path)
+}
+
+func TestWatcherIgnoresExcludedFiles(t *testing.T) {
+	folder := t.TempDir()
+	watcher := startWatcher(t, folder)
+
+	writeFile(t, filepath.Join(folder, "Show.S01E01.nfo"))
+	path := filepath.Join(folder, "Show.S01E02.mkv")
+	writeFile(t, path)
+
+	select {
+	case event := <-watcher.Events:
+		if event.Path != path {
+			t.Errorf("got event for %v, want only events for %v", event.Path, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestWatcherReportsFilesInFoldersMovedIn(t *testing.T) {
+	folder := t.TempDir()
+	outside := t.TempDir()
+	watcher := startWatcher(t, folder)
+
+	season := filepath.Join(outside, "Season 1")
+	if err := os.MkdirAll(season, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(season, "Show.S01E01.mkv"))
+
+	target := filepath.Join(folder, "Show")
+	if err := os.Rename(outside, target); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, watcher, Added, filepath.Join(target, "Season 1", "Show.S01E01.mkv"))
+
+	// Files added to the moved folder are reported as well
+	path := filepath.Join(target, "Season 1", "Show.S01E02.mkv")
+	writeFile(t, path)
+	waitFor(t, watcher, Added, path)
+}
+
+func TestWatcherReportsRemovedFilesAndFolders(t *testing.T) {
+	folder := t.TempDir()
+	show := filepath.Join(folder, "Show")
+	if err := os.MkdirAll(show, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(show, "Show.S01E01.mkv")
+	writeFile(t, path)
+	watcher := startWatcher(t, folder)
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, watcher, Removed, path)
+
+	if err := os.Remove(show); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, watcher, Removed, show)
+}
@@ -0,0 +1,264 @@
+// Package webui implements the web UI for reviewing the deletions that require approval
+package webui
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bjw-s/series-cleanup/internal/state"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// maxRequestSize is the largest request body accepted by the API
+const maxRequestSize = 64 * 1024
+
+// StoreFunc calls fn with an open state database
+type StoreFunc func(fn func(store *state.Store) error) error
+
+// ShowSkipper keeps track of the show folders that are always skipped
+type ShowSkipper interface {
+	SkipShow(folder string) error
+	UnskipShow(folder string) error
+	SkippedShows() ([]string, error)
+}
+
+// UI serves the web UI and its API
+type UI struct {
+	withStore StoreFunc
+	skipper   ShowSkipper
+	token     string
+	mux       *http.ServeMux
+}
+
+// approvalRequest changes the status of the deletions of an episode, a season or a show.
+// Path selects a single episode, otherwise all episodes of Folder are selected, optionally
+// limited to Season.
+type approvalRequest struct {
+	Status state.ApprovalStatus `json:"status"`
+	Path   string               `json:"path"`
+	Folder string               `json:"folder"`
+	Season *int                 `json:"season"`
+}
+
+type skippedShowRequest struct {
+	Folder string `json:"folder"`
+}
+
+// New creates a new UI instance. Every request must include token as the password of HTTP
+// basic authentication, so browsers ask for it, or as a bearer token.
+func New(withStore StoreFunc, skipper ShowSkipper, token string) *UI {
+	ui := &UI{
+		withStore: withStore,
+		skipper:   skipper,
+		token:     token,
+		mux:       http.NewServeMux(),
+	}
+
+	static, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err)
+	}
+	ui.mux.Handle("/", http.FileServer(http.FS(static)))
+	ui.mux.HandleFunc("/api/approvals", ui.handleApprovals)
+	ui.mux.HandleFunc("/api/skipped-shows", ui.handleSkippedShows)
+
+	return ui
+}
+
+// ServeHTTP implements http.Handler
+func (ui *UI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !ui.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="series-cleanup", charset="UTF-8"`)
+		writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+		return
+	}
+	ui.mux.ServeHTTP(w, r)
+}
+
+func (ui *UI) authorized(r *http.Request) bool {
+	// Without a token nobody could be trusted to approve deletions
+	if ui.token == "" {
+		return false
+	}
+
+	token := ""
+	if _, password, ok := r.BasicAuth(); ok {
+		token = password
+	} else if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(ui.token)) == 1
+}
+
+func (ui *UI) handleApprovals(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var approvals map[string]state.Approval
+		err := ui.withStore(func(store *state.Store) (err error) {
+			approvals, err = store.Approvals()
+			return err
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, sortedApprovals(approvals))
+
+	case http.MethodPost:
+		var request approvalRequest
+		if err := readJSON(w, r, &request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		match, err := request.matcher()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		var updated int
+		err = ui.withStore(func(store *state.Store) (err error) {
+			updated, err = store.DecideApprovals(match, request.Status, time.Now())
+			return err
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"updated": updated})
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func (request approvalRequest) matcher() (func(state.Approval) bool, error) {
+	switch request.Status {
+	case state.ApprovalApproved, state.ApprovalRejected, state.ApprovalPending:
+	default:
+		return nil, errors.New("status must be approved, rejected or pending")
+	}
+
+	if request.Path != "" {
+		return func(approval state.Approval) bool {
+			return approval.Path == request.Path
+		}, nil
+	}
+
+	if request.Folder == "" {
+		return nil, errors.New("either path or folder is required")
+	}
+	return func(approval state.Approval) bool {
+		if approval.Folder != request.Folder {
+			return false
+		}
+		return request.Season == nil || approval.Season == *request.Season
+	}, nil
+}
+
+func (ui *UI) handleSkippedShows(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		folders, err := ui.skipper.SkippedShows()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		skippedShows := make([]skippedShowRequest, 0, len(folders))
+		for _, folder := range folders {
+			skippedShows = append(skippedShows, skippedShowRequest{Folder: folder})
+		}
+		sort.Slice(skippedShows, func(i, j int) bool {
+			return strings.ToLower(skippedShows[i].Folder) < strings.ToLower(skippedShows[j].Folder)
+		})
+		writeJSON(w, http.StatusOK, skippedShows)
+
+	case http.MethodPost, http.MethodDelete:
+		var request skippedShowRequest
+		if err := readJSON(w, r, &request); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if request.Folder == "" {
+			writeError(w, http.StatusBadRequest, errors.New("folder is required"))
+			return
+		}
+
+		var err error
+		if r.Method == http.MethodDelete {
+			err = ui.skipper.UnskipShow(request.Folder)
+		} else {
+			err = ui.skipper.SkipShow(request.Folder)
+			if err == nil {
+				err = ui.withStore(func(store *state.Store) error {
+					return store.RemoveApprovals(request.Folder)
+				})
+			}
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+func sortedApprovals(approvals map[string]state.Approval) []state.Approval {
+	sorted := make([]state.Approval, 0, len(approvals))
+	for _, approval := range approvals {
+		sorted = append(sorted, approval)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if !strings.EqualFold(a.Show, b.Show) {
+			return strings.ToLower(a.Show) < strings.ToLower(b.Show)
+		}
+		if a.Season != b.Season {
+			return a.Season < b.Season
+		}
+		if a.Episode != b.Episode {
+			return a.Episode < b.Episode
+		}
+		return a.Path < b.Path
+	})
+	return sorted
+}
+
+// readJSON decodes the JSON body of r into value.
+// Requiring a JSON content type prevents other sites from submitting plain HTML forms to the API.
+func readJSON(w http.ResponseWriter, r *http.Request, value interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		return errors.New("content type must be application/json")
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	writeJSON(w, statusCode, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,188 @@
+package webui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bjw-s/series-cleanup/internal/state"
+)
+
+const testToken = "secret"
+
+// testSkipper keeps the skipped shows in memory
+type testSkipper map[string]bool
+
+func (skipper testSkipper) SkipShow(folder string) error {
+	skipper[folder] = true
+	return nil
+}
+
+func (skipper testSkipper) UnskipShow(folder string) error {
+	delete(skipper, folder)
+	return nil
+}
+
+func (skipper testSkipper) SkippedShows() ([]string, error) {
+	var folders []string
+	for folder := range skipper {
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+func newTestUI(t *testing.T) (*UI, *state.Store) {
+	t.Helper()
+	store, err := state.Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	err = store.SetPendingApprovals([]state.Approval{
+		{Path: "/media/Show/Season 1/S01E01.mkv", Folder: "Show", Show: "Show", Season: 1, Episode: 1},
+		{Path: "/media/Show/Season 2/S02E01.mkv", Folder: "Show", Show: "Show", Season: 2, Episode: 1},
+		{Path: "/media/Other/Season 1/S01E01.mkv", Folder: "Other", Show: "Other", Season: 1, Episode: 1},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return New(func(fn func(store *state.Store) error) error {
+		return fn(store)
+	}, testSkipper{}, testToken), store
+}
+
+func serve(ui *UI, method, url, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, url, strings.NewReader(body))
+	request.SetBasicAuth("admin", testToken)
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	recorder := httptest.NewRecorder()
+	ui.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIndex(t *testing.T) {
+	ui, _ := newTestUI(t)
+
+	response := serve(ui, http.MethodGet, "/", "")
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), "Pending deletions") {
+		t.Errorf("GET / = %v", response.Code)
+	}
+}
+
+func TestApproveSeason(t *testing.T) {
+	ui, store := newTestUI(t)
+
+	response := serve(ui, http.MethodPost, "/api/approvals", `{"status": "approved", "folder": "Show", "season": 2}`)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `"updated":1`) {
+		t.Fatalf("POST /api/approvals = %v %v", response.Code, response.Body)
+	}
+
+	approvals, err := store.Approvals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, approval := range approvals {
+		want := state.ApprovalPending
+		if path == "/media/Show/Season 2/S02E01.mkv" {
+			want = state.ApprovalApproved
+		}
+		if approval.Status != want {
+			t.Errorf("%v: status = %v, want %v", path, approval.Status, want)
+		}
+	}
+
+	response = serve(ui, http.MethodGet, "/api/approvals", "")
+	var listed []state.Approval
+	if err := json.Unmarshal(response.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 3 || listed[0].Show != "Other" || listed[2].Season != 2 {
+		t.Errorf("GET /api/approvals = %+v, want approvals sorted by show and episode", listed)
+	}
+}
+
+func TestRejectsInvalidRequests(t *testing.T) {
+	ui, _ := newTestUI(t)
+
+	for _, body := range []string{
+		`{"status": "maybe", "folder": "Show"}`,
+		`{"status": "approved"}`,
+		`{"status": "approved", "folder": "Show", "unknown": true}`,
+	} {
+		if response := serve(ui, http.MethodPost, "/api/approvals", body); response.Code != http.StatusBadRequest {
+			t.Errorf("POST %v = %v, want 400", body, response.Code)
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/api/approvals", strings.NewReader(`{"status": "approved", "folder": "Show"}`))
+	request.SetBasicAuth("admin", testToken)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	ui.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("POST with form content type = %v, want 400", recorder.Code)
+	}
+}
+
+func TestRequiresToken(t *testing.T) {
+	ui, _ := newTestUI(t)
+
+	for name, authorize := range map[string]func(*http.Request){
+		"no token":    func(*http.Request) {},
+		"wrong token": func(r *http.Request) { r.SetBasicAuth("admin", "wrong") },
+		"bearer":      func(r *http.Request) { r.Header.Set("Authorization", "Bearer wrong") },
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/skipped-shows", strings.NewReader(`{"folder": "Show"}`))
+		request.Header.Set("Content-Type", "application/json")
+		authorize(request)
+		recorder := httptest.NewRecorder()
+		ui.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusUnauthorized || recorder.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%v: POST /api/skipped-shows = %v, want 401 with a basic authentication challenge", name, recorder.Code)
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/api/approvals", nil)
+	request.Header.Set("Authorization", "Bearer "+testToken)
+	recorder := httptest.NewRecorder()
+	ui.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("GET /api/approvals with bearer token = %v, want 200", recorder.Code)
+	}
+
+	// Without a configured token nobody is let in
+	unprotected := New(nil, testSkipper{}, "")
+	if response := serve(unprotected, http.MethodGet, "/", ""); response.Code != http.StatusUnauthorized {
+		t.Errorf("GET / without a configured token = %v, want 401", response.Code)
+	}
+}
+
+func TestSkipShow(t *testing.T) {
+	ui, store := newTestUI(t)
+
+	if response := serve(ui, http.MethodPost, "/api/skipped-shows", `{"folder": "Show"}`); response.Code != http.StatusNoContent {
+		t.Fatalf("POST /api/skipped-shows = %v %v", response.Code, response.Body)
+	}
+
+	response := serve(ui, http.MethodGet, "/api/skipped-shows", "")
+	if response.Code != http.StatusOK || strings.TrimSpace(response.Body.String()) != `[{"folder":"Show"}]` {
+		t.Fatalf("GET /api/skipped-shows = %v %v", response.Code, response.Body)
+	}
+	if approvals, _ := store.Approvals(); len(approvals) != 1 {
+		t.Errorf("Approvals() = %v, want the approvals of the skipped show to be removed", approvals)
+	}
+
+	if response := serve(ui, http.MethodDelete, "/api/skipped-shows", `{"folder": "Show"}`); response.Code != http.StatusNoContent {
+		t.Fatalf("DELETE /api/skipped-shows = %v %v", response.Code, response.Body)
+	}
+	if response := serve(ui, http.MethodGet, "/api/skipped-shows", ""); strings.TrimSpace(response.Body.String()) != `[]` {
+		t.Errorf("GET /api/skipped-shows = %v after DELETE", response.Body)
+	}
+}